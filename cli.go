@@ -0,0 +1,802 @@
+package main
+
+import (
+	"bingoviewer/dbdiff"
+	"bingoviewer/query"
+	"bingoviewer/schema"
+	"bingoviewer/store"
+	"bingoviewer/structgen"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/nokusukun/bingo"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit codes of the headless commands.
+const (
+	EXIT_OK        = 0
+	EXIT_ERROR     = 1
+	EXIT_USAGE     = 2
+	EXIT_NOT_FOUND = 3
+	EXIT_INVALID   = 4
+	EXIT_DIFFERENT = 5
+)
+
+// errInvalid is returned when documents don't match the schema they are validated against.
+var errInvalid = errors.New("documents don't match the schema")
+
+// errDifferent is returned when the databases compared by diff don't hold the same documents.
+var errDifferent = errors.New("the databases differ")
+
+// usageError is an error in the arguments of a command.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+func usageErrorf(format string, a ...any) error {
+	return usageError(fmt.Sprintf(format, a...))
+}
+
+// command is a headless subcommand, run gets the arguments after the command name.
+type command struct {
+	usage string
+	help  string
+	run   func(c *cli, args []string) error
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"collections": {"collections <database>", "list the collections and their document counts", runCollections},
+		"fields":      {"fields <database> <collection>", "list the fields bingo recorded for a collection", runFields},
+		"count":       {"count <database> <collection> [--where expr]", "count the documents, optionally only the matching ones", runCount},
+		"get":         {"get <database> <collection> <key>", "print the document stored under key", runGet},
+		"find":        {"find <database> <collection> --where expr [--limit n]", "print the documents matching a query expression", runFind},
+		"dump":        {"dump <database> [collection...]", "print every document of the database or of the given collections", runDump},
+		"put":         {"put <database> <collection> [key] [--data json] [--upsert]", "insert a document read from --data or stdin", runPut},
+		"delete":      {"delete <database> <collection> <key>...", "delete documents, all of them or none", runDelete},
+		"schema":      {"schema <database> <collection> [--sample n] [--json-schema]", "report the fields, types and values seen in a collection", runSchema},
+		"validate":    {"validate <database> <collection> --schema file", "check every document against a JSON Schema or the validate tags of a Go struct", runValidate},
+		"diff":        {"diff <old> <new> [--documents]", "compare two databases, collections by name and documents by key", runDiff},
+		"stats":       {"stats <database> [--largest n]", "report the file layout and the size of every collection", runStats},
+		"struct":      {"struct <database> <collection> [--sample n] [--name Type] [--package name]", "generate a Go struct for the documents of a collection", runStruct},
+		"help":        {"help", "show this list", runHelp},
+	}
+}
+
+// cli holds the flags shared by every command.
+type cli struct {
+	name    string
+	format  string
+	timeout time.Duration
+	out     io.Writer
+}
+
+// commandArgs returns the headless command the arguments start with and the arguments it gets.
+// A database file named like a command is opened in the viewer instead, as is anything after --.
+// Directories don't count, a checkout of the sources has one named schema.
+func commandArgs(args []string) (string, []string, bool) {
+	if len(args) == 0 {
+		return "", nil, false
+	}
+	if _, ok := commands[args[0]]; !ok {
+		return "", nil, false
+	}
+	if info, err := os.Stat(args[0]); err == nil && info.Mode().IsRegular() {
+		return "", nil, false
+	}
+	return args[0], args[1:], true
+}
+
+// runCommand runs a headless command and returns the exit code.
+func runCommand(name string, args []string) int {
+	cmd := commands[name]
+	c := &cli{name: name, out: os.Stdout}
+	err := cmd.run(c, args)
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return EXIT_OK
+	case errors.Is(err, flag.ErrHelp):
+		return EXIT_OK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "bingoviewer %v: %v\nUsage: bingoviewer %v\n", name, err, cmd.usage)
+		return EXIT_USAGE
+	case errors.Is(err, bingo.ErrDocumentNotFound):
+		fmt.Fprintf(os.Stderr, "bingoviewer %v: %v\n", name, strings.ReplaceAll(err.Error(), "\n", ": "))
+		return EXIT_NOT_FOUND
+	case errors.Is(err, errInvalid):
+		fmt.Fprintf(os.Stderr, "bingoviewer %v: %v\n", name, err)
+		return EXIT_INVALID
+	case errors.Is(err, errDifferent):
+		fmt.Fprintf(os.Stderr, "bingoviewer %v: %v\n", name, err)
+		return EXIT_DIFFERENT
+	}
+	fmt.Fprintf(os.Stderr, "bingoviewer %v: %v\n", name, strings.ReplaceAll(err.Error(), "\n", ": "))
+	return EXIT_ERROR
+}
+
+// flags returns a flag set with the shared flags, parse it with c.parse.
+func (c *cli) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.StringVar(&c.format, "format", "table", "output format, table or json")
+	fs.DurationVar(&c.timeout, "timeout", OPEN_TIMEOUT, "how long to wait for the database lock")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: bingoviewer %v\n\n", commands[c.name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse reads the flags and checks the number of positional arguments, max < 0 allows any number.
+func (c *cli) parse(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil, err
+		}
+		return nil, usageError(err.Error())
+	}
+	if len(positional) < min || (max >= 0 && len(positional) > max) {
+		return nil, usageErrorf("wrong number of arguments")
+	}
+	if c.format != "table" && c.format != "json" {
+		return nil, usageErrorf("unknown format %q", c.format)
+	}
+	if c.timeout <= 0 {
+		// nothing could cancel the wait for the lock
+		return nil, usageErrorf("timeout must be positive, got %v", c.timeout)
+	}
+	return positional, nil
+}
+
+// open opens the database with a shared lock unless the command writes to it.
+func (c *cli) open(path string, write bool) (*store.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return store.Open(path, store.Options{ReadOnly: !write, Timeout: c.timeout})
+}
+
+func (c *cli) json(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (c *cli) table(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	if header != nil {
+		if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			// keep the table on one line per row
+			row[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cell)
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func runHelp(c *cli, args []string) error {
+	fmt.Fprintf(c.out, "Usage: bingoviewer [database] to browse a database, or bingoviewer <command>\n\n")
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var rows [][]string
+	for _, name := range names {
+		rows = append(rows, []string{"  " + commands[name].usage, commands[name].help})
+	}
+	if err := c.table(nil, rows); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "\nEvery command takes --format table|json and --timeout. Exit codes: 0 ok, 1 error, 2 bad arguments, 3 not found, 4 invalid documents, 5 databases differ.\n")
+	return nil
+}
+
+func runCollections(c *cli, args []string) error {
+	pos, err := c.parse(c.flags(), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	colls, err := db.GetCollections()
+	if err != nil {
+		return err
+	}
+	type collection struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	var result []collection
+	var rows [][]string
+	for _, name := range colls {
+		count, err := db.Count(name)
+		if err != nil {
+			return err
+		}
+		result = append(result, collection{name, count})
+		rows = append(rows, []string{name, fmt.Sprint(count)})
+	}
+	if c.format == "json" {
+		return c.json(result)
+	}
+	return c.table([]string{"COLLECTION", "DOCUMENTS"}, rows)
+}
+
+func runFields(c *cli, args []string) error {
+	pos, err := c.parse(c.flags(), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	fields, err := db.FieldsOf(pos[1])
+	if err != nil {
+		return fmt.Errorf("no fields recorded for %v: %w", pos[1], err)
+	}
+	type field struct {
+		Name    string   `json:"name"`
+		Aliases []string `json:"aliases"`
+	}
+	var result []field
+	var rows [][]string
+	for _, aliases := range fields {
+		result = append(result, field{aliases[0], aliases})
+		rows = append(rows, []string{aliases[0], strings.Join(aliases, ", ")})
+	}
+	if c.format == "json" {
+		return c.json(result)
+	}
+	return c.table([]string{"FIELD", "ALIASES"}, rows)
+}
+
+// compileWhere turns --where into a bingo filter, an empty expression matches everything.
+func compileWhere(where string) (func(doc kmap) bool, error) {
+	if where == "" {
+		return nil, nil
+	}
+	q, err := query.Compile(where)
+	if err != nil {
+		return nil, usageErrorf("invalid query: %v", err)
+	}
+	return func(doc kmap) bool {
+		return q.Match(doc)
+	}, nil
+}
+
+func runCount(c *cli, args []string) error {
+	fs := c.flags()
+	where := fs.String("where", "", "only count documents matching this query expression")
+	pos, err := c.parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	filter, err := compileWhere(*where)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	count := 0
+	if filter == nil {
+		count, err = db.Count(pos[1])
+	} else {
+		err = store.Find(db, pos[1], bingo.Query[kmap]{Filter: filter}, func(_ []byte, _ *kmap) error {
+			count++
+			return nil
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if c.format == "json" {
+		return c.json(map[string]int{"count": count})
+	}
+	_, err = fmt.Fprintln(c.out, count)
+	return err
+}
+
+// withKey returns a copy of the document carrying its key under KEY_COLUMN, like exports do.
+func withKey(key []byte, doc kmap) map[string]any {
+	out := make(map[string]any, len(doc)+1)
+	for k, v := range doc {
+		out[k] = v
+	}
+	out[KEY_COLUMN] = string(key)
+	return out
+}
+
+func runGet(c *cli, args []string) error {
+	pos, err := c.parse(c.flags(), args, 3, 3)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	doc, err := store.Get[kmap](db, pos[1], []byte(pos[2]))
+	if err != nil {
+		return err
+	}
+	if c.format == "json" {
+		return c.json(withKey([]byte(pos[2]), doc))
+	}
+	rows := [][]string{{KEY_COLUMN, pos[2]}}
+	var names []string
+	for k := range doc {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		rows = append(rows, []string{k, csvValue(doc[k])})
+	}
+	return c.table([]string{"FIELD", "VALUE"}, rows)
+}
+
+// printDocuments writes the documents of a collection matching q as a JSON array or a table
+// with a column per recorded field. It returns how many documents were printed.
+func (c *cli) printDocuments(db *store.DB, collection string, q bingo.Query[kmap]) (int, error) {
+	columns, _ := db.FieldsOf(collection)
+	count := 0
+	if c.format == "json" {
+		if _, err := io.WriteString(c.out, "["); err != nil {
+			return 0, err
+		}
+		err := store.Find(db, collection, q, func(key []byte, doc *kmap) error {
+			data, err := json.Marshal(withKey(key, *doc))
+			if err != nil {
+				return err
+			}
+			sep := ",\n  "
+			if count == 0 {
+				sep = "\n  "
+			}
+			count++
+			_, err = fmt.Fprintf(c.out, "%v%s", sep, data)
+			return err
+		})
+		if err != nil {
+			return count, err
+		}
+		end := "]\n"
+		if count > 0 {
+			end = "\n]\n"
+		}
+		_, err = io.WriteString(c.out, end)
+		return count, err
+	}
+
+	header := []string{KEY_COLUMN}
+	for _, col := range columns {
+		header = append(header, col[0])
+	}
+	var rows [][]string
+	err := store.Find(db, collection, q, func(key []byte, doc *kmap) error {
+		row := []string{string(key)}
+		if len(columns) == 0 {
+			data, _ := json.Marshal(*doc)
+			row = append(row, string(data))
+		}
+		for _, col := range columns {
+			row = append(row, csvValue(firstAlias(*doc, col)))
+		}
+		rows = append(rows, row)
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+	if len(columns) == 0 {
+		header = append(header, "DOCUMENT")
+	}
+	return count, c.table(header, rows)
+}
+
+func runFind(c *cli, args []string) error {
+	fs := c.flags()
+	where := fs.String("where", "", "query expression the documents have to match")
+	limit := fs.Int("limit", 0, "print at most this many documents, 0 prints all")
+	pos, err := c.parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	if *where == "" {
+		return usageErrorf("--where is required")
+	}
+	if *limit < 0 {
+		return usageErrorf("limit can't be negative, got %v", *limit)
+	}
+	filter, err := compileWhere(*where)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	_, err = c.printDocuments(db, pos[1], bingo.Query[kmap]{Filter: filter, Count: *limit})
+	return err
+}
+
+func runDump(c *cli, args []string) error {
+	pos, err := c.parse(c.flags(), args, 1, -1)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	colls := pos[1:]
+	if len(colls) == 0 {
+		if colls, err = db.GetCollections(); err != nil {
+			return err
+		}
+	}
+
+	// a dump cut short by a full disk or a closed pipe has to fail, scripts can't tell it from a complete one
+	if c.format == "json" {
+		// an object with a document array per collection, written as it is read
+		if _, err := fmt.Fprint(c.out, "{"); err != nil {
+			return err
+		}
+		for i, coll := range colls {
+			name, _ := json.Marshal(coll)
+			sep := ","
+			if i == 0 {
+				sep = ""
+			}
+			if _, err := fmt.Fprintf(c.out, "%v\n%s: ", sep, name); err != nil {
+				return err
+			}
+			if _, err := c.printDocuments(db, coll, bingo.Query[kmap]{}); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprint(c.out, "}\n")
+		return err
+	}
+	for i, coll := range colls {
+		sep := ""
+		if i > 0 {
+			sep = "\n"
+		}
+		if _, err := fmt.Fprintf(c.out, "%v== %v ==\n", sep, coll); err != nil {
+			return err
+		}
+		if _, err := c.printDocuments(db, coll, bingo.Query[kmap]{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runPut(c *cli, args []string) error {
+	fs := c.flags()
+	data := fs.String("data", "", "the document as JSON, read from stdin when empty")
+	upsert := fs.Bool("upsert", false, "overwrite the document if the key exists")
+	pos, err := c.parse(fs, args, 2, 3)
+	if err != nil {
+		return err
+	}
+
+	src := *data
+	if src == "" {
+		in, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		src = string(in)
+	}
+	value, err := parseJSON(src)
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	doc, ok := value.(map[string]any)
+	if !ok {
+		return errors.New("a document has to be a JSON object")
+	}
+	var key []byte
+	if len(pos) == 3 {
+		key = []byte(pos[2])
+	}
+
+	db, err := c.open(pos[0], true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ids, err := db.Insert(pos[1], [][]byte{key}, []any{doc}, *upsert)
+	if err != nil {
+		return err
+	}
+	if c.format == "json" {
+		return c.json(map[string]string{"key": string(ids[0])})
+	}
+	_, err = fmt.Fprintln(c.out, string(ids[0]))
+	return err
+}
+
+func runDelete(c *cli, args []string) error {
+	pos, err := c.parse(c.flags(), args, 3, -1)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var keys [][]byte
+	for _, k := range pos[2:] {
+		keys = append(keys, []byte(k))
+	}
+	if err := db.Delete(pos[1], keys); err != nil {
+		return err
+	}
+	if c.format == "json" {
+		return c.json(map[string]int{"deleted": len(keys)})
+	}
+	_, err = fmt.Fprintf(c.out, "deleted %v document(s)\n", len(keys))
+	return err
+}
+
+func runStruct(c *cli, args []string) error {
+	fs := c.flags()
+	sample := fs.Int("sample", 1000, "number of documents to sample, newest first, 0 reads every document")
+	name := fs.String("name", "", "name of the struct, derived from the collection by default")
+	pkg := fs.String("package", "", "package clause to write, none by default")
+	pos, err := c.parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	src, err := structgen.Collection(db, pos[1], *sample, structgen.Options{Name: *name, Package: *pkg})
+	if err != nil {
+		return err
+	}
+	_, err = c.out.Write(src)
+	return err
+}
+
+func runSchema(c *cli, args []string) error {
+	fs := c.flags()
+	sample := fs.Int("sample", 0, "number of documents to scan, newest first, 0 scans every document")
+	jsonSchema := fs.Bool("json-schema", false, "print the report as a JSON Schema")
+	pos, err := c.parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := scanCollection(db, pos[1], *sample)
+	if err != nil {
+		return err
+	}
+	switch {
+	case *jsonSchema:
+		return c.json(report.JSONSchema())
+	case c.format == "json":
+		return c.json(report)
+	}
+	if _, err := fmt.Fprintf(c.out, "%v from %v\n\n", report.Collection, describeScan(&report)); err != nil {
+		return err
+	}
+	return c.table(schemaHeader, schemaRows(&report))
+}
+
+func runValidate(c *cli, args []string) error {
+	fs := c.flags()
+	schemaPath := fs.String("schema", "", "JSON Schema file, or Go file with the struct as file.go:Type")
+	pos, err := c.parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	if *schemaPath == "" {
+		return usageErrorf("--schema is required")
+	}
+	checker, err := schema.Load(*schemaPath)
+	if err != nil {
+		return err
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	checked, failures, err := validateCollection(db, pos[1], checker)
+	if err != nil {
+		return err
+	}
+	if c.format == "json" {
+		type failure struct {
+			Key        string             `json:"key"`
+			Violations []schema.Violation `json:"violations"`
+		}
+		out := []failure{}
+		for _, f := range failures {
+			out = append(out, failure{f.key, f.violations})
+		}
+		if err := c.json(out); err != nil {
+			return err
+		}
+	} else {
+		var rows [][]string
+		for _, f := range failures {
+			for _, v := range f.violations {
+				rows = append(rows, []string{f.key, v.Path, v.Rule, v.Message})
+			}
+		}
+		if len(rows) > 0 {
+			if err := c.table([]string{"KEY", "FIELD", "RULE", "MESSAGE"}, rows); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(c.out); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(c.out, "%v of %v document(s) don't match\n", len(failures), checked); err != nil {
+			return err
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v of %v document(s): %w", len(failures), checked, errInvalid)
+	}
+	return nil
+}
+
+func runDiff(c *cli, args []string) error {
+	fs := c.flags()
+	documents := fs.Bool("documents", false, "list every document that differs and its changed fields")
+	pos, err := c.parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	old, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+	current, err := c.open(pos[1], false)
+	if err != nil {
+		return err
+	}
+	defer current.Close()
+
+	result, err := dbdiff.Compare(old, current)
+	if err != nil {
+		return err
+	}
+	if !*documents {
+		for i := range result.Collections {
+			result.Collections[i].Documents = nil
+		}
+	}
+	if c.format == "json" {
+		if err := c.json(result); err != nil {
+			return err
+		}
+	} else {
+		var rows [][]string
+		for _, coll := range result.Collections {
+			rows = append(rows, []string{coll.Name, string(coll.Status), fmt.Sprint(coll.Added), fmt.Sprint(coll.Removed),
+				fmt.Sprint(coll.Modified), fmt.Sprint(coll.Unchanged)})
+		}
+		if err := c.table([]string{"COLLECTION", "STATUS", "ADDED", "REMOVED", "MODIFIED", "UNCHANGED"}, rows); err != nil {
+			return err
+		}
+		if *documents && !result.Equal() {
+			rows = nil
+			for _, coll := range result.Collections {
+				for _, doc := range coll.Documents {
+					if doc.Status != dbdiff.Modified {
+						rows = append(rows, []string{coll.Name, doc.Key, string(doc.Status), "", "", ""})
+						continue
+					}
+					for _, f := range doc.Fields {
+						oldValue, newValue := dbdiff.Format(f.Old), dbdiff.Format(f.New)
+						switch f.Status {
+						case dbdiff.Added:
+							oldValue = ""
+						case dbdiff.Removed:
+							newValue = ""
+						}
+						rows = append(rows, []string{coll.Name, doc.Key, string(f.Status), f.Path, oldValue, newValue})
+					}
+				}
+			}
+			if _, err := fmt.Fprintln(c.out); err != nil {
+				return err
+			}
+			if err := c.table([]string{"COLLECTION", "KEY", "CHANGE", "FIELD", "OLD", "NEW"}, rows); err != nil {
+				return err
+			}
+		}
+	}
+	if !result.Equal() {
+		return errDifferent
+	}
+	return nil
+}
+
+func runStats(c *cli, args []string) error {
+	fs := c.flags()
+	largest := fs.Int("largest", STATS_LARGEST, "how many of the largest documents to list per collection")
+	pos, err := c.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if *largest < 0 {
+		return usageErrorf("--largest can't be negative")
+	}
+	db, err := c.open(pos[0], false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	colls, err := db.GetCollections()
+	if err != nil {
+		return err
+	}
+	stats, err := db.Stats(colls, *largest)
+	if err != nil {
+		return err
+	}
+	if c.format == "json" {
+		return c.json(stats)
+	}
+	return writeStats(c.out, &stats, func(s string) string {
+		return "== " + s + " =="
+	})
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// a database file named like a command is opened rather than run as the command
+func TestCommandArgs(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if name, args, ok := commandArgs([]string{"dump", "app.db"}); !ok || name != "dump" || !reflect.DeepEqual(args, []string{"app.db"}) {
+		t.Fatalf("dump app.db: %q %q %v", name, args, ok)
+	}
+	if err := os.Mkdir("stats", 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if name, _, ok := commandArgs([]string{"stats", "app.db"}); !ok || name != "stats" {
+		t.Fatal("the directory stats was taken for a database file")
+	}
+	if err := os.WriteFile("dump", nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := commandArgs([]string{"dump"}); ok {
+		t.Fatal("the file dump was taken for the command")
+	}
+	if _, _, ok := commandArgs([]string{"--", "stats"}); ok {
+		t.Fatal("-- stats was taken for the command")
+	}
+	opts, err := parseArgs([]string{"--", "dump"})
+	if err != nil || opts.DatabaseFile != "dump" {
+		t.Fatalf("-- dump: %q %v", opts.DatabaseFile, err)
+	}
+	if _, err := parseArgs([]string{"--", filepath.Join("missing", "stats")}); err == nil {
+		t.Fatal("a missing file after -- was accepted")
+	}
+}
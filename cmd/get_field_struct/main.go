@@ -1,32 +1,44 @@
 package main
 
 import (
+	"bingoviewer/store"
+	"bingoviewer/structgen"
+	"flag"
 	"fmt"
-	"github.com/nokusukun/bingo"
+	"os"
+	"time"
 )
 
-type Empty map[string]any
-
-func (Empty) Key() []byte {
-	return nil
-}
-
 func main() {
-	driver, err := bingo.NewDriver(bingo.DriverConfiguration{
-		Filename: "opaws.db",
-	})
-	if err != nil {
-		panic(err)
+	sample := flag.Int("sample", 1000, "number of documents to sample, newest first, 0 reads every document")
+	name := flag.String("name", "", "name of the struct, derived from the collection by default")
+	pkg := flag.String("package", "", "package clause to write, none by default")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: get_field_struct [--sample n] [--name Type] [--package name] <database> <collection>\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
 	}
 
-	users := bingo.CollectionFrom[Empty](driver, "users")
-	docs, err := users.Find(func(doc Empty) bool {
-		return true
-	})
+	src, err := generate(flag.Arg(0), flag.Arg(1), *sample, structgen.Options{Name: *name, Package: *pkg})
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "get_field_struct: %v\n", err)
+		os.Exit(1)
 	}
-	for _, doc := range docs {
-		fmt.Println(doc)
+	os.Stdout.Write(src)
+}
+
+func generate(path, collection string, sample int, opts structgen.Options) ([]byte, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	driver, err := store.Open(path, store.Options{ReadOnly: true, Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
 	}
+	defer driver.Close()
+	return structgen.Collection(driver, collection, sample, opts)
 }
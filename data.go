@@ -0,0 +1,637 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/termenv"
+	"github.com/nokusukun/bingo"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PAGE_SIZE is how many documents are decoded at a time while loading a collection.
+const PAGE_SIZE = 500
+
+// WINDOW_PAGES is how many pages of rows are kept around the cursor, rows further away are dropped
+// and read again when the cursor comes back to them.
+const WINDOW_PAGES = 4
+
+// KEY_COLUMN is the name the bbolt key of a document is shown under. The table has a hidden column
+// of that name, K shows it.
+const KEY_COLUMN = "_key"
+
+// pageRequest is the page of rows to load: the one below key, or above it when above is set. A nil key
+// is the top of the collection, or its bottom when loading above. from starts the page on that row
+// instead, reset replaces the loaded rows with the page instead of adding it and skip passes over that
+// many rows first. A sorted collection only skips rows when from isn't in it anymore.
+type pageRequest struct {
+	reset bool
+	above bool
+	key   []byte
+	from  []byte
+	skip  int
+}
+
+// rowsLoadedMsg carries a page of rows loaded in the background, more is set when the collection goes
+// on past the page in the direction it was loaded. offset is the position of the first row in the
+// collection, -1 when it isn't known.
+type rowsLoadedMsg struct {
+	seq       int
+	page      pageRequest
+	offset    int
+	keys      [][]byte
+	rows      [][]any
+	cleanRows [][]any
+	more      bool
+	loadErr   string
+	err       error
+	// flat holds the nested fields of the documents of the page when the table is flattened
+	flat flattening
+	docs []kmap
+}
+
+// rowLayout is what a page needs to know about the table to build its rows.
+type rowLayout struct {
+	fields  [][]string
+	columns [][]string
+	flatten int
+}
+
+// rowCountMsg carries the estimated number of documents in the collection.
+type rowCountMsg struct {
+	seq   int
+	count int
+	err   error
+}
+
+// buildRow picks the first alias of every column present in the document stored under key,
+// row is what the table shows and cleanRow keeps the decoded values.
+func buildRow(columns [][]string, compact bool, key []byte, doc kmap) (row []any, cleanRow []any) {
+	for _, colnames := range columns {
+		val, ok := columnValue(key, doc, colnames)
+		if !ok {
+			row = append(row, "(None)")
+			cleanRow = append(cleanRow, nil)
+			continue
+		}
+		text, isCompact := compactCell(val)
+		switch v := val.(type) {
+		case float64:
+			// large numbers stay in plain notation, 1700000000 rather than 1.7e+09
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			if !compact || !isCompact {
+				text = fmt.Sprintf("%v", val)
+			}
+		}
+		row = append(row, strings.Map(func(r rune) rune {
+			if unicode.IsPrint(r) {
+				return r
+			}
+			return -1
+		}, text))
+		cleanRow = append(cleanRow, val)
+	}
+	return row, cleanRow
+}
+
+// addRow returns the function loading a page passes its documents to, the rows are added to msg.
+func (msg *rowsLoadedMsg) addRow(layout rowLayout) func(key []byte, doc *kmap) error {
+	return func(key []byte, doc *kmap) error {
+		row, cleanRow := buildRow(layout.columns, layout.flatten > 0, key, *doc)
+		if len(row) != len(layout.columns) {
+			msg.loadErr = fmt.Sprintf("Row has %v columns, expected %v", len(row), len(layout.columns))
+			return nil
+		}
+		if layout.flatten > 0 {
+			msg.docs = append(msg.docs, *doc)
+		}
+		msg.keys = append(msg.keys, append([]byte{}, key...))
+		msg.rows = append(msg.rows, row)
+		msg.cleanRows = append(msg.cleanRows, cleanRow)
+		return nil
+	}
+}
+
+// isKeyColumn reports whether a column is the KEY_COLUMN showing the keys of the documents.
+func isKeyColumn(colnames []string) bool {
+	return len(colnames) == 1 && colnames[0] == KEY_COLUMN
+}
+
+// columnValue returns the value a column shows for the document stored under key.
+func columnValue(key []byte, doc kmap, colnames []string) (any, bool) {
+	if isKeyColumn(colnames) {
+		return string(key), true
+	}
+	return lookupColumn(doc, colnames)
+}
+
+// flattenPage finds the nested fields of the documents of a flattened page.
+func (msg *rowsLoadedMsg) flattenPage(layout rowLayout) {
+	if layout.flatten > 0 {
+		msg.flat = flattenFields(layout.fields, msg.docs, layout.flatten)
+		msg.docs = nil
+	}
+}
+
+// loadPage decodes a page of the collection, it doesn't touch the model so it can run in the background.
+func loadPage(db *store.DB, collection string, layout rowLayout, filter func(doc kmap) bool, page pageRequest, seq int) rowsLoadedMsg {
+	msg := rowsLoadedMsg{seq: seq, page: page, offset: -1}
+	add := msg.addRow(layout)
+	q := bingo.Query[kmap]{Count: PAGE_SIZE, Filter: filter, Skip: page.skip}
+	if page.above {
+		msg.more, msg.err = store.PageAbove(db, collection, q, page.key, add)
+		return msg
+	}
+	key := page.key
+	switch {
+	case page.from != nil:
+		key, q.Skip = keyAfter(page.from), 0
+	case key == nil:
+		msg.offset = page.skip
+	}
+	next, err := store.Page(db, collection, q, key, add)
+	msg.more, msg.err = next != nil, err
+	return msg
+}
+
+// loadSorted decodes a page of a sorted collection, order holds the keys of all its rows in sorted order.
+func loadSorted(db *store.DB, collection string, layout rowLayout, order [][]byte, page pageRequest, seq int) rowsLoadedMsg {
+	msg := rowsLoadedMsg{seq: seq, page: page}
+	index := func(key []byte) int {
+		for i, k := range order {
+			if bytes.Equal(k, key) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	start, end := page.skip, len(order)
+	switch {
+	case page.from != nil:
+		if i := index(page.from); i >= 0 {
+			start = i
+		}
+	case page.key != nil:
+		i := index(page.key)
+		if i < 0 {
+			msg.err = fmt.Errorf("%v is not in the sorted rows anymore", string(page.key))
+			return msg
+		}
+		if page.above {
+			end = i
+		} else {
+			start = i + 1
+		}
+	}
+	if page.above {
+		start = max(0, end-PAGE_SIZE)
+		msg.more = start > 0
+	} else {
+		start = min(start, end)
+		end = min(end, start+PAGE_SIZE)
+		msg.more = end < len(order)
+	}
+	msg.offset = start
+	q := bingo.Query[kmap]{Keys: order[start:end]}
+	msg.err = store.Find(db, collection, q, msg.addRow(layout))
+	return msg
+}
+
+// getData resets the table for the active collection and loads its first page in the background,
+// the documents are counted in the background as well.
+func (m *Model) getData() (tea.Cmd, error) {
+	if err := m.layoutColumns(); err != nil {
+		return nil, err
+	}
+
+	m.recordKey = nil
+	m.jump = nil
+	m.rowCount = -1
+	m.selected = map[string]bool{}
+	m.visualAnchor = -1
+	m.visualBase = nil
+	m.clearRows()
+	m.announce = true
+	sort := m.ensureSort()
+	load := m.requestPage(pageRequest{reset: true})
+	return tea.Batch(load, m.recount(), sort), nil
+}
+
+// layoutColumns reads the fields of the active collection, the table columns are the same
+// fields or, when flattening, their nested fields found in the first page of documents and
+// in the pages loaded since. The key column comes first when it is shown.
+func (m *Model) layoutColumns() error {
+	collection := m.collections[m.activeCollection]
+	fields, err := m.driver.FieldsOf(collection)
+	if err != nil {
+		return err
+	}
+	m.fields = fields
+	if m.flatten > 0 {
+		docs, err := sampleDocs(m.driver, collection, m.rowFilter())
+		if err != nil {
+			return err
+		}
+		if of := fmt.Sprintf("%v\x00%v", collection, m.flatten); of != m.flatOf {
+			m.flat, m.flatOf = flattening{}, of
+		}
+		m.flat.merge(flattenFields(fields, docs, m.flatten))
+	}
+	m.setColumns()
+	return nil
+}
+
+// setColumns lays the columns out from the fields and how they are flattened.
+func (m *Model) setColumns() {
+	m.columns = m.fields
+	m.columnFields = make([]int, len(m.fields))
+	for i := range m.fields {
+		m.columnFields[i] = i
+	}
+	if m.flatten > 0 {
+		m.columns, m.columnFields = flattenColumns(m.fields, m.flat)
+	}
+	if m.showKey {
+		m.columns = append([][]string{{KEY_COLUMN}}, m.columns...)
+		m.columnFields = append([]int{-1}, m.columnFields...)
+	}
+}
+
+// clearRows empties the table, pages still loading for it are dropped.
+func (m *Model) clearRows() {
+	m.loadSeq++
+	m.rowKeys = nil
+	m.rowData = nil
+	m.cleanRowData = nil
+	m.rowOffset = 0
+	m.loadedFirst = true
+	m.loadedAll = true
+	m.loadingRows = false
+	m.resetTable()
+}
+
+// reloadRows empties the table and loads it again in the background around the record stored under
+// current, or around the row that took its place when it is gone. The cursor keeps its column.
+func (m *Model) reloadRows(current []byte) tea.Cmd {
+	x, y := m.table.GetCursorLocation()
+	position, _ := m.rowPosition(y)
+	m.clearRows()
+	m.reloadColumn = x
+	if current == nil {
+		return m.requestPage(pageRequest{reset: true})
+	}
+	return m.requestPage(pageRequest{reset: true, from: current, skip: max(0, position-1)})
+}
+
+// keyAfter is the smallest key sorting after key, a page loaded below it starts on key itself.
+func keyAfter(key []byte) []byte {
+	return append(append([]byte{}, key...), 0)
+}
+
+// requestPage starts loading a page in the background. A reset drops the pages still loading, other
+// pages wait for the one loading to arrive.
+func (m *Model) requestPage(page pageRequest) tea.Cmd {
+	if page.reset {
+		m.loadSeq++
+	} else if m.loadingRows {
+		return nil
+	}
+	m.loadingRows = true
+	db, collection, filter, seq := m.driver, m.collections[m.activeCollection], m.rowFilter(), m.loadSeq
+	layout := rowLayout{fields: m.fields, columns: m.columns, flatten: m.flatten}
+	if order := m.sortedKeys(); order != nil {
+		return func() tea.Msg {
+			msg := loadSorted(db, collection, layout, order, page, seq)
+			msg.flattenPage(layout)
+			return msg
+		}
+	}
+	return func() tea.Msg {
+		msg := loadPage(db, collection, layout, filter, page, seq)
+		msg.flattenPage(layout)
+		return msg
+	}
+}
+
+// loadCollection switches the table over to the active collection.
+func (m *Model) loadCollection() tea.Cmd {
+	cmd, err := m.getData()
+	if err != nil {
+		m.Error(fmt.Sprintf("Failed to get columns: %v", err))
+	}
+	return cmd
+}
+
+// addPage puts a loaded page into the table. Rows past WINDOW_PAGES pages on the other side of the
+// window are dropped so the table never holds more than that, the cursor stays on its record.
+func (m *Model) addPage(msg rowsLoadedMsg) {
+	if msg.err != nil {
+		m.Error(fmt.Sprintf("Failed to load rows: %v", msg.err))
+	}
+	if msg.loadErr != "" {
+		m.Error(msg.loadErr)
+	}
+	page := msg.page
+	current, _ := m.cursorKey()
+	x, _ := m.table.GetCursorLocation()
+
+	switch {
+	case page.reset:
+		m.rowKeys, m.rowData, m.cleanRowData = msg.keys, msg.rows, msg.cleanRows
+		m.rowOffset = msg.offset
+		current = nil
+		if page.above {
+			// the last rows of the collection
+			m.loadedAll, m.loadedFirst = true, !msg.more
+			if len(msg.keys) > 0 {
+				current = msg.keys[len(msg.keys)-1]
+			}
+		} else {
+			m.loadedAll, m.loadedFirst = !msg.more || msg.err != nil, msg.offset == 0
+			if len(msg.keys) > 0 {
+				current = msg.keys[0]
+			}
+		}
+		if m.loadedFirst {
+			m.rowOffset = 0
+		}
+		x = m.reloadColumn
+		m.reloadColumn = 0
+		m.visualAnchor = -1
+		m.visualBase = nil
+	case page.above:
+		m.loadedFirst = !msg.more || msg.err != nil
+		m.rowKeys = append(append([][]byte{}, msg.keys...), m.rowKeys...)
+		m.rowData = append(append([][]any{}, msg.rows...), m.rowData...)
+		m.cleanRowData = append(append([][]any{}, msg.cleanRows...), m.cleanRowData...)
+		switch {
+		case m.loadedFirst:
+			m.rowOffset = 0
+		case m.rowOffset >= 0:
+			m.rowOffset = max(0, m.rowOffset-len(msg.keys))
+		}
+		m.shiftVisual(len(msg.keys))
+		if drop := len(m.rowKeys) - WINDOW_PAGES*PAGE_SIZE; drop > 0 {
+			end := len(m.rowKeys) - drop
+			m.rowKeys, m.rowData, m.cleanRowData = m.rowKeys[:end:end], m.rowData[:end:end], m.cleanRowData[:end:end]
+			m.loadedAll = false
+		}
+	default:
+		m.loadedAll = !msg.more || msg.err != nil
+		if len(msg.rows) == 0 {
+			return
+		}
+		start := len(m.rowData)
+		m.rowKeys = append(m.rowKeys, msg.keys...)
+		m.rowData = append(m.rowData, msg.rows...)
+		m.cleanRowData = append(m.cleanRowData, msg.cleanRows...)
+		drop := len(m.rowKeys) - WINDOW_PAGES*PAGE_SIZE
+		if drop <= 0 {
+			// rows added at the end don't move the cursor, the table can take them as they are
+			rows := make([][]any, 0, len(msg.rows))
+			for i := start; i < len(m.rowData); i++ {
+				rows = append(rows, m.displayRow(i))
+			}
+			var err error
+			m.table, err = m.table.AddRows(rows)
+			if err != nil {
+				m.Error(fmt.Sprintf("Failed to render table: %v", err))
+			}
+			return
+		}
+		m.rowKeys, m.rowData, m.cleanRowData = m.rowKeys[drop:], m.rowData[drop:], m.cleanRowData[drop:]
+		m.loadedFirst = false
+		if m.rowOffset >= 0 {
+			m.rowOffset += drop
+		}
+		m.shiftVisual(-drop)
+	}
+
+	m.resetTable()
+	var err error
+	m.table, err = m.table.AddRows(m.displayRows())
+	if err != nil {
+		m.Error(fmt.Sprintf("Failed to render table: %v", err))
+	}
+	for i := 0; i < x; i++ {
+		m.table.CursorRight()
+	}
+	for i, k := range m.rowKeys {
+		if bytes.Equal(k, current) {
+			m.setCursor(i)
+			break
+		}
+	}
+}
+
+// shiftVisual keeps the anchor of a range selection on its row when rows are added or dropped in
+// front of it. Once the anchor is dropped the rows selected so far stay selected.
+func (m *Model) shiftVisual(delta int) {
+	if m.visualAnchor < 0 {
+		return
+	}
+	m.visualAnchor += delta
+	if m.visualAnchor < 0 || m.visualAnchor >= len(m.rowKeys) {
+		m.visualBase = map[string]bool{}
+		for k := range m.selected {
+			m.visualBase[k] = true
+		}
+		m.visualAnchor = max(0, min(m.visualAnchor, len(m.rowKeys)-1))
+	}
+}
+
+// loadMoreIfNeeded starts loading the page below or above the window once the cursor gets close to its end.
+func (m *Model) loadMoreIfNeeded() tea.Cmd {
+	if m.driver == nil || len(m.collections) == 0 || m.loadingRows || len(m.rowKeys) == 0 {
+		return nil
+	}
+	_, y := m.table.GetCursorLocation()
+	switch {
+	case !m.loadedAll && len(m.rowData)-y <= PAGE_SIZE/2:
+		return m.requestPage(pageRequest{key: m.rowKeys[len(m.rowKeys)-1]})
+	case !m.loadedFirst && y < PAGE_SIZE/2:
+		return m.requestPage(pageRequest{above: true, key: m.rowKeys[0]})
+	}
+	return nil
+}
+
+func (m Model) rowsLoaded(msg rowsLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.loadSeq {
+		return m, nil
+	}
+	m.loadingRows = false
+	if m.flatten > 0 && msg.flat != nil && m.flat.merge(msg.flat) {
+		return m, m.relayout(msg.page)
+	}
+	if jump := m.jump; jump != nil && msg.page.reset {
+		found := len(msg.keys) > 0 && bytes.Equal(msg.keys[0], jump.key)
+		switch {
+		case found:
+			m.jump, m.announce = nil, false
+			m.addPage(msg)
+			m.landJump(*jump)
+			return m, m.loadMoreIfNeeded()
+		case m.sortScan == nil:
+			m.jump, m.announce = nil, false
+			m.jumpMissing(*jump)
+			if len(m.rowKeys) > 0 {
+				// the rows shown before the jump stay
+				return m, m.loadMoreIfNeeded()
+			}
+			return m, m.requestPage(pageRequest{reset: true})
+		}
+		// the row isn't in the sorted rows yet, the jump waits for the sort running in the background
+	}
+	if msg.page.reset && msg.page.skip > 0 && len(msg.keys) == 0 && msg.err == nil {
+		m.Error(fmt.Sprintf("Row %v is out of range", msg.page.skip+1))
+		return m, m.requestPage(pageRequest{reset: true})
+	}
+	m.addPage(msg)
+	if m.announce && msg.page.reset {
+		m.announce = false
+		if q := m.activeQuery(); q != nil {
+			m.Info(fmt.Sprintf("Loaded %v row(s) matching %v", len(m.rowData), q.Source))
+		} else {
+			m.Info(fmt.Sprintf("Loaded %v row(s)", len(m.rowData)))
+		}
+	}
+	m.syncRecord()
+	return m, m.loadMoreIfNeeded()
+}
+
+// showFirst moves the cursor to the first row, loading the top of the collection when it isn't loaded.
+func (m *Model) showFirst() tea.Cmd {
+	if m.loadedFirst {
+		m.setCursor(0)
+		return nil
+	}
+	return m.requestPage(pageRequest{reset: true})
+}
+
+// showLast moves the cursor to the last row, loading the bottom of the collection when it isn't loaded.
+func (m *Model) showLast() tea.Cmd {
+	if m.loadedAll {
+		m.setCursor(len(m.rowKeys) - 1)
+		return nil
+	}
+	return m.requestPage(pageRequest{reset: true, above: true})
+}
+
+// showRow moves the cursor to the n-th row of the collection, counting from zero.
+func (m *Model) showRow(n int) tea.Cmd {
+	if m.rowOffset >= 0 && n >= m.rowOffset && n < m.rowOffset+len(m.rowKeys) {
+		m.setCursor(n - m.rowOffset)
+		return nil
+	}
+	return m.requestPage(pageRequest{reset: true, skip: n})
+}
+
+// rowPosition is the position of a loaded row in the collection counting from one, it is unknown
+// after a jump until the window reaches either end of the collection.
+func (m Model) rowPosition(row int) (int, bool) {
+	switch {
+	case m.rowOffset >= 0:
+		return m.rowOffset + row + 1, true
+	case m.loadedAll && m.rowCount >= 0:
+		return m.rowCount - len(m.rowKeys) + row + 1, true
+	}
+	return 0, false
+}
+
+func (m Model) RenderRowCount() string {
+	count := m.renderLoaded()
+	if len(m.selected) > 0 {
+		count = fmt.Sprintf("%v, %v selected", count, len(m.selected))
+	}
+	if m.watch != nil {
+		count += ", watching"
+	}
+	return count
+}
+
+func (m Model) renderLoaded() string {
+	what, total := "row(s)", fmt.Sprintf("~%v", m.rowCount)
+	if q := m.activeQuery(); q != nil {
+		what, total = fmt.Sprintf("match(es) for /%v", q.Source), fmt.Sprint(m.rowCount)
+	}
+	sorting := ""
+	if m.sortScan != nil {
+		sorting = ", sorting..."
+	}
+	switch {
+	case m.loadedFirst && m.loadedAll:
+		return fmt.Sprintf("%v %v%v", len(m.rowData), what, sorting)
+	case m.rowCount < 0:
+		return fmt.Sprintf("loaded %v %v, counting...%v", len(m.rowData), what, sorting)
+	}
+	first, known := m.rowPosition(0)
+	if !known {
+		return fmt.Sprintf("loaded %v of %v %v%v", len(m.rowData), total, what, sorting)
+	}
+	return fmt.Sprintf("loaded %v-%v of %v %v%v", first, first+len(m.rowData)-1, total, what, sorting)
+}
+
+// rowKey returns the bbolt key of a loaded row.
+func (m Model) rowKey(row int) ([]byte, bool) {
+	if row < 0 || row >= len(m.rowKeys) {
+		return nil, false
+	}
+	return m.rowKeys[row], true
+}
+
+// cursorKey returns the key of the row under the table cursor.
+func (m Model) cursorKey() ([]byte, bool) {
+	_, y := m.table.GetCursorLocation()
+	return m.rowKey(y)
+}
+
+// lookup reads the document of a loaded row straight from the database by its key.
+func (m Model) lookup(row int) (kmap, error) {
+	key, ok := m.rowKey(row)
+	if !ok {
+		return nil, fmt.Errorf("row %v is not loaded", row+1)
+	}
+	return store.Get[kmap](m.driver, m.collections[m.activeCollection], key)
+}
+
+// syncRecord keeps the document shown in the record view in step with the cursor.
+func (m *Model) syncRecord() {
+	if !m.showRecord {
+		return
+	}
+	key, ok := m.cursorKey()
+	if !ok {
+		m.record, m.recordKey, m.recordErr = nil, nil, nil
+		return
+	}
+	if m.recordKey != nil && bytes.Equal(key, m.recordKey) {
+		return
+	}
+	_, y := m.table.GetCursorLocation()
+	m.recordKey = key
+	m.record, m.recordErr = m.lookup(y)
+}
+
+// copyRecord puts the document under the cursor on the clipboard as JSON.
+func (m *Model) copyRecord() {
+	_, y := m.table.GetCursorLocation()
+	doc, err := m.lookup(y)
+	if err != nil {
+		m.Error(fmt.Sprintf("Copy failed: %v", err))
+		return
+	}
+	key, _ := m.rowKey(y)
+	r, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		m.Error(fmt.Sprintf("Copy failed: %v", err))
+		return
+	}
+	termenv.Copy(string(r))
+	m.Success(fmt.Sprintf("Copied %v to the clipboard", string(key)))
+}
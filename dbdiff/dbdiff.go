@@ -0,0 +1,271 @@
+package dbdiff
+
+import (
+	"bingoviewer/store"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Status is how a collection, document or field differs between the two databases.
+type Status string
+
+const (
+	Same     Status = "same"
+	Added    Status = "added"
+	Removed  Status = "removed"
+	Modified Status = "modified"
+)
+
+// Field is a value that differs between the two versions of a document, Path is dotted for members
+// and indexed for array elements: address.city, tags[1].
+type Field struct {
+	Path   string `json:"path"`
+	Status Status `json:"status"`
+	Old    any    `json:"old"`
+	New    any    `json:"new"`
+}
+
+// Document is a document that was added, removed or modified, Old is nil when it was added and New when it was removed.
+type Document struct {
+	Key    string         `json:"key"`
+	Status Status         `json:"status"`
+	Fields []Field        `json:"fields,omitempty"`
+	Old    map[string]any `json:"-"`
+	New    map[string]any `json:"-"`
+}
+
+// Collection is the comparison of the collections of the same name in both databases.
+type Collection struct {
+	Name      string     `json:"name"`
+	Status    Status     `json:"status"`
+	Added     int        `json:"added"`
+	Removed   int        `json:"removed"`
+	Modified  int        `json:"modified"`
+	Unchanged int        `json:"unchanged"`
+	Documents []Document `json:"documents,omitempty"`
+}
+
+// Result is the comparison of two databases, collections are in the order of the old database
+// followed by those only the new one has.
+type Result struct {
+	Old         string       `json:"old"`
+	New         string       `json:"new"`
+	Collections []Collection `json:"collections"`
+}
+
+// Equal reports whether both databases hold the same documents.
+func (r Result) Equal() bool {
+	for _, c := range r.Collections {
+		if c.Status != Same {
+			return false
+		}
+	}
+	return true
+}
+
+// document is what documents are decoded into, bingo wants a type with a Key method.
+type document map[string]any
+
+func (document) Key() []byte {
+	return nil
+}
+
+// Compare pairs the collections of both databases by name and their documents by key. Documents are
+// told apart by a hash of what is stored, only those that differ are decoded to find the fields that changed.
+func Compare(old, current *store.DB) (Result, error) {
+	result := Result{Old: old.Path, New: current.Path}
+	if old.IsSnapshot() {
+		result.Old = old.Source
+	}
+	if current.IsSnapshot() {
+		result.New = current.Source
+	}
+	oldColls, err := old.GetCollections()
+	if err != nil {
+		return result, err
+	}
+	newColls, err := current.GetCollections()
+	if err != nil {
+		return result, err
+	}
+
+	names := append([]string{}, oldColls...)
+	inOld := map[string]bool{}
+	for _, name := range oldColls {
+		inOld[name] = true
+	}
+	inNew := map[string]bool{}
+	for _, name := range newColls {
+		inNew[name] = true
+		if !inOld[name] {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		var a, b *store.DB
+		if inOld[name] {
+			a = old
+		}
+		if inNew[name] {
+			b = current
+		}
+		c, err := compareCollection(name, a, b)
+		if err != nil {
+			return result, fmt.Errorf("%v: %w", name, err)
+		}
+		result.Collections = append(result.Collections, c)
+	}
+	return result, nil
+}
+
+// compareCollection compares a collection of both databases, a nil database doesn't have it.
+func compareCollection(name string, old, current *store.DB) (Collection, error) {
+	c := Collection{Name: name, Status: Same}
+	oldPrints, newPrints := map[string]uint64{}, map[string]uint64{}
+	var err error
+	if old != nil {
+		if oldPrints, err = old.Fingerprints(name); err != nil {
+			return c, err
+		}
+	}
+	if current != nil {
+		if newPrints, err = current.Fingerprints(name); err != nil {
+			return c, err
+		}
+	}
+
+	keys := make([]string, 0, len(oldPrints)+len(newPrints))
+	for k := range oldPrints {
+		keys = append(keys, k)
+	}
+	for k := range newPrints {
+		if _, ok := oldPrints[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		oldPrint, inOld := oldPrints[k]
+		newPrint, inNew := newPrints[k]
+		if inOld && inNew && oldPrint == newPrint {
+			c.Unchanged++
+			continue
+		}
+		d := Document{Key: k}
+		if inOld {
+			doc, err := store.Get[document](old, name, []byte(k))
+			if err != nil {
+				return c, err
+			}
+			d.Old = doc
+		}
+		if inNew {
+			doc, err := store.Get[document](current, name, []byte(k))
+			if err != nil {
+				return c, err
+			}
+			d.New = doc
+		}
+		switch {
+		case !inOld:
+			d.Status = Added
+			c.Added++
+		case !inNew:
+			d.Status = Removed
+			c.Removed++
+		default:
+			d.Fields = Fields(d.Old, d.New)
+			if len(d.Fields) == 0 {
+				// stored differently, the same once decoded
+				c.Unchanged++
+				continue
+			}
+			d.Status = Modified
+			c.Modified++
+		}
+		c.Documents = append(c.Documents, d)
+	}
+
+	switch {
+	case old == nil:
+		c.Status = Added
+	case current == nil:
+		c.Status = Removed
+	case len(c.Documents) > 0:
+		c.Status = Modified
+	}
+	return c, nil
+}
+
+// Fields lists the values that differ between two versions of a document, objects are compared
+// member by member and arrays element by element.
+func Fields(old, current map[string]any) []Field {
+	return diffValue(nil, "", old, current)
+}
+
+func diffValue(fields []Field, path string, old, current any) []Field {
+	switch o := old.(type) {
+	case map[string]any:
+		if n, ok := current.(map[string]any); ok {
+			keys := make([]string, 0, len(o)+len(n))
+			for k := range o {
+				keys = append(keys, k)
+			}
+			for k := range n {
+				if _, ok := o[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				childPath := k
+				if path != "" {
+					childPath = path + "." + k
+				}
+				ov, inOld := o[k]
+				nv, inNew := n[k]
+				switch {
+				case !inOld:
+					fields = append(fields, Field{Path: childPath, Status: Added, New: nv})
+				case !inNew:
+					fields = append(fields, Field{Path: childPath, Status: Removed, Old: ov})
+				default:
+					fields = diffValue(fields, childPath, ov, nv)
+				}
+			}
+			return fields
+		}
+	case []any:
+		if n, ok := current.([]any); ok {
+			for i := 0; i < max(len(o), len(n)); i++ {
+				childPath := fmt.Sprintf("%v[%v]", path, i)
+				switch {
+				case i >= len(o):
+					fields = append(fields, Field{Path: childPath, Status: Added, New: n[i]})
+				case i >= len(n):
+					fields = append(fields, Field{Path: childPath, Status: Removed, Old: o[i]})
+				default:
+					fields = diffValue(fields, childPath, o[i], n[i])
+				}
+			}
+			return fields
+		}
+	}
+	if !reflect.DeepEqual(old, current) {
+		fields = append(fields, Field{Path: path, Status: Modified, Old: old, New: current})
+	}
+	return fields
+}
+
+// Format renders a value the way the diff shows it, as compact JSON.
+func Format(v any) string {
+	r, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(r)
+}
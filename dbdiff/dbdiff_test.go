@@ -0,0 +1,133 @@
+package dbdiff
+
+import (
+	"bingoviewer/store"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newDatabase writes the documents of every collection, keyed by their id field.
+func newDatabase(t *testing.T, name string, collections map[string][]map[string]any) *store.DB {
+	t.Helper()
+	db, err := store.Open(filepath.Join(t.TempDir(), name), store.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for collection, docs := range collections {
+		var keys [][]byte
+		var values []any
+		for _, doc := range docs {
+			keys = append(keys, []byte(doc["id"].(string)))
+			values = append(values, doc)
+		}
+		if err := db.RegisterCollection(collection, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Insert(collection, keys, values, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestCompare(t *testing.T) {
+	old := newDatabase(t, "old.db", map[string][]map[string]any{
+		"users": {
+			{"id": "ann", "age": 30.0},
+			{"id": "bob", "age": 40.0, "tags": []any{"a"}},
+			{"id": "cid", "age": 50.0},
+		},
+		"orders": {{"id": "1"}},
+		"tags":   {{"id": "x"}},
+	})
+	current := newDatabase(t, "new.db", map[string][]map[string]any{
+		"users": {
+			{"id": "ann", "age": 30.0},
+			{"id": "bob", "age": 41.0, "tags": []any{"a", "b"}},
+			{"id": "dan", "age": 20.0},
+		},
+		"logs": {{"id": "1"}},
+		"tags": {{"id": "x"}},
+	})
+
+	result, err := Compare(old, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Equal() {
+		t.Fatal("databases with different documents compared equal")
+	}
+	collections := map[string]Collection{}
+	for _, c := range result.Collections {
+		collections[c.Name] = c
+	}
+	if len(collections) != 4 {
+		t.Fatalf("expected 4 collections, got %+v", result.Collections)
+	}
+	for name, status := range map[string]Status{"users": Modified, "orders": Removed, "logs": Added, "tags": Same} {
+		if got := collections[name].Status; got != status {
+			t.Errorf("%v is %v, expected %v", name, got, status)
+		}
+	}
+
+	users := collections["users"]
+	if users.Added != 1 || users.Removed != 1 || users.Modified != 1 || users.Unchanged != 1 {
+		t.Fatalf("users: %+v", users)
+	}
+	var statuses []string
+	for _, d := range users.Documents {
+		statuses = append(statuses, d.Key+" "+string(d.Status))
+	}
+	if want := []string{"bob modified", "cid removed", "dan added"}; !reflect.DeepEqual(statuses, want) {
+		t.Fatalf("documents %v, want %v", statuses, want)
+	}
+	want := []Field{
+		{Path: "age", Status: Modified, Old: 40.0, New: 41.0},
+		{Path: "tags[1]", Status: Added, New: "b"},
+	}
+	if got := users.Documents[0].Fields; !reflect.DeepEqual(got, want) {
+		t.Fatalf("fields of bob %+v, want %+v", got, want)
+	}
+
+	same, err := Compare(old, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !same.Equal() {
+		t.Fatalf("a database differs from itself: %+v", same)
+	}
+}
+
+func TestFields(t *testing.T) {
+	old := map[string]any{
+		"name":    "ann",
+		"address": map[string]any{"city": "Oslo", "zip": "0150"},
+		"tags":    []any{"a", "b", "c"},
+		"kind":    map[string]any{"a": 1.0},
+	}
+	current := map[string]any{
+		"name":    "ann",
+		"address": map[string]any{"city": "Bergen", "street": "Main"},
+		"tags":    []any{"a", "x"},
+		"kind":    "plain",
+		"age":     3.0,
+	}
+	want := []Field{
+		{Path: "address.city", Status: Modified, Old: "Oslo", New: "Bergen"},
+		{Path: "address.street", Status: Added, New: "Main"},
+		{Path: "address.zip", Status: Removed, Old: "0150"},
+		{Path: "age", Status: Added, New: 3.0},
+		{Path: "kind", Status: Modified, Old: map[string]any{"a": 1.0}, New: "plain"},
+		{Path: "tags[1]", Status: Modified, Old: "b", New: "x"},
+		{Path: "tags[2]", Status: Removed, Old: "c"},
+	}
+	if got := Fields(old, current); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+	if got := Fields(old, old); got != nil {
+		t.Fatalf("a document differs from itself: %+v", got)
+	}
+}
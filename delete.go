@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bingoviewer/flasher"
+	"bingoviewer/store"
+	"bytes"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"sort"
+)
+
+// toggleSelected adds the row under the cursor to the selection or takes it out again.
+func (m *Model) toggleSelected() {
+	key, ok := m.cursorKey()
+	if !ok {
+		return
+	}
+	if m.selected[string(key)] {
+		delete(m.selected, string(key))
+	} else {
+		m.selected[string(key)] = true
+	}
+	m.rebuildTable()
+}
+
+// toggleVisual starts selecting every row between here and wherever the cursor moves,
+// pressed again the rows stay selected.
+func (m *Model) toggleVisual() {
+	if m.visualAnchor >= 0 {
+		m.visualAnchor = -1
+		m.visualBase = nil
+		return
+	}
+	_, y := m.table.GetCursorLocation()
+	if _, ok := m.rowKey(y); !ok {
+		return
+	}
+	m.visualAnchor = y
+	m.visualBase = map[string]bool{}
+	for k := range m.selected {
+		m.visualBase[k] = true
+	}
+	m.selectVisual()
+}
+
+// selectVisual selects the rows between the visual anchor and the cursor, on top of what was selected before.
+func (m *Model) selectVisual() {
+	if m.visualAnchor < 0 {
+		return
+	}
+	_, y := m.table.GetCursorLocation()
+	from, to := min(m.visualAnchor, y), max(m.visualAnchor, y)
+	selected := map[string]bool{}
+	for k := range m.visualBase {
+		selected[k] = true
+	}
+	for i := from; i <= to && i < len(m.rowKeys); i++ {
+		selected[string(m.rowKeys[i])] = true
+	}
+	if len(selected) == len(m.selected) {
+		same := true
+		for k := range selected {
+			if !m.selected[k] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return
+		}
+	}
+	m.selected = selected
+	m.rebuildTable()
+}
+
+// clearSelection drops the selection, it returns false if there was nothing to clear.
+func (m *Model) clearSelection() bool {
+	if len(m.selected) == 0 && m.visualAnchor < 0 {
+		return false
+	}
+	m.selected = map[string]bool{}
+	m.visualAnchor = -1
+	m.visualBase = nil
+	m.rebuildTable()
+	return true
+}
+
+// askDelete asks to delete the selected rows, or the row under the cursor when nothing is selected.
+func (m Model) askDelete() (tea.Model, tea.Cmd) {
+	if m.driver == nil || len(m.rowData) == 0 {
+		return m, nil
+	}
+	if m.driver.ReadOnly {
+		m.Error(fmt.Sprintf("Can't delete, %v", store.ErrReadOnly))
+		return m, nil
+	}
+
+	// the selection also holds rows that were scrolled out of the loaded window
+	var keys [][]byte
+	for key := range m.selected {
+		keys = append(keys, []byte(key))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i], keys[j]) < 0
+	})
+	if len(keys) == 0 {
+		key, ok := m.cursorKey()
+		if !ok {
+			return m, nil
+		}
+		keys = [][]byte{key}
+	}
+	m.deleting = keys
+
+	collection := m.collections[m.activeCollection]
+	question := fmt.Sprintf("Delete %v from %v?", string(keys[0]), collection)
+	if len(keys) > 1 {
+		question = fmt.Sprintf("Delete %v documents from %v?", len(keys), collection)
+	}
+	return m, flasher.AskFlash(deleteFlash, question+"\n\nThis can't be undone.")
+}
+
+// deleteRows removes the documents waiting on the delete question and takes their rows out of the table.
+func (m Model) deleteRows() (tea.Model, tea.Cmd) {
+	keys := m.deleting
+	m.deleting = nil
+	if err := m.driver.Delete(m.collections[m.activeCollection], keys); err != nil {
+		m.Error(fmt.Sprintf("Delete failed, nothing was deleted: %v", err))
+		return m, nil
+	}
+
+	deleted := map[string]bool{}
+	for _, key := range keys {
+		deleted[string(key)] = true
+	}
+
+	// the cursor moves to the first row after it that survives, or the last one before it
+	_, y := m.table.GetCursorLocation()
+	var next []byte
+	for i := y; i < len(m.rowKeys) && next == nil; i++ {
+		if !deleted[string(m.rowKeys[i])] {
+			next = m.rowKeys[i]
+		}
+	}
+	for i := y - 1; i >= 0 && next == nil; i-- {
+		if !deleted[string(m.rowKeys[i])] {
+			next = m.rowKeys[i]
+		}
+	}
+
+	var rowKeys [][]byte
+	var rowData, cleanRowData [][]any
+	for i, key := range m.rowKeys {
+		if deleted[string(key)] {
+			continue
+		}
+		rowKeys = append(rowKeys, key)
+		rowData = append(rowData, m.rowData[i])
+		cleanRowData = append(cleanRowData, m.cleanRowData[i])
+	}
+	m.rowKeys, m.rowData, m.cleanRowData = rowKeys, rowData, cleanRowData
+	m.forgetSorted(deleted)
+	if m.rowCount >= 0 {
+		m.rowCount -= len(keys)
+	}
+	m.selected = map[string]bool{}
+	m.visualAnchor = -1
+	m.visualBase = nil
+
+	m.rebuildTableAt(next)
+	if len(m.rowData) == 0 {
+		m.showRecord = false
+	}
+	m.recordKey = nil
+
+	m.Success(fmt.Sprintf("Deleted %v document(s) from %v", len(keys), m.collections[m.activeCollection]))
+	return m, tea.Batch(m.ClearInfoAfter("3s"), m.loadMoreIfNeeded())
+}
+
+// displayRows returns the rows as the table shows them.
+func (m Model) displayRows() [][]any {
+	rows := make([][]any, len(m.rowData))
+	for i := range m.rowData {
+		rows[i] = m.displayRow(i)
+	}
+	return rows
+}
+
+// displayRow marks a selected row in its first cell, colours the cells breaking the attached schema
+// and the rows watch mode saw added or changed.
+func (m Model) displayRow(i int) []any {
+	row := m.rowData[i]
+	invalid := m.invalidPaths(m.rowKeys[i])
+	changeStyle, changed := m.changeStyle(string(m.rowKeys[i]))
+	if !m.selected[string(m.rowKeys[i])] && invalid == nil && !changed {
+		return row
+	}
+	row = append([]any{}, row...)
+	for c, aliases := range m.columns {
+		switch {
+		case invalidColumn(aliases, invalid):
+			row[c] = invalidCellStyle.Render(fmt.Sprint(row[c]))
+		case changed:
+			row[c] = changeStyle.Render(fmt.Sprint(row[c]))
+		}
+	}
+	if m.selected[string(m.rowKeys[i])] && len(row) > 0 {
+		row[0] = fmt.Sprintf("● %v", row[0])
+	}
+	return row
+}
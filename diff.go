@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bingoviewer/dbdiff"
+	"bingoviewer/store"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"path/filepath"
+	"strings"
+)
+
+var diffModifiedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#e5c07b"))
+
+// diffDoneMsg carries the comparison of the open database with another one.
+type diffDoneMsg struct {
+	seq    int
+	result dbdiff.Result
+	err    error
+}
+
+// diffPanel compares the open database with other. cursor is the selected line of the list and
+// document, when set, the line whose documents are shown side by side scrolled down offset lines.
+type diffPanel struct {
+	seq      int
+	other    string
+	running  bool
+	result   *dbdiff.Result
+	err      error
+	cursor   int
+	document bool
+	offset   int
+}
+
+// diffLine is a line of the diff list, a collection or, when doc isn't -1, one of its documents.
+type diffLine struct {
+	collection int
+	doc        int
+}
+
+// compareDatabases opens the other database next to the open one and compares them, a file locked
+// by another process is compared from a snapshot.
+func compareDatabases(db *store.DB, other string, opts store.Options, seq int) tea.Cmd {
+	return func() tea.Msg {
+		opts.ReadOnly = true
+		otherDB, err := store.Open(other, opts)
+		if errors.Is(err, store.ErrLocked) {
+			otherDB, err = store.OpenSnapshot(other, opts)
+		}
+		if err != nil {
+			return diffDoneMsg{seq: seq, err: err}
+		}
+		defer otherDB.Close()
+		result, err := dbdiff.Compare(db, otherDB)
+		return diffDoneMsg{seq: seq, result: result, err: err}
+	}
+}
+
+func (m Model) showDiffPrompt() (tea.Model, tea.Cmd) {
+	if m.driver == nil {
+		return m, nil
+	}
+	m.diffInput = textinput.New()
+	m.diffInput.Prompt = "compare with: "
+	m.diffInput.Placeholder = "path of another database"
+	if m.diffView.other != "" {
+		m.diffInput.SetValue(m.diffView.other)
+	} else {
+		m.diffInput.SetValue(filepath.Dir(m.DatabaseFile) + string(filepath.Separator))
+	}
+	m.diffInput.CursorEnd()
+	m.state = DiffPrompt
+	return m, m.diffInput.Focus()
+}
+
+func (m Model) updateDiffPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		m.state = Normal
+		m.diffInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		path := strings.TrimSpace(m.diffInput.Value())
+		if path == "" {
+			return m, nil
+		}
+		m.diffInput.Blur()
+		return m, m.startDiff(path)
+	}
+	var cmd tea.Cmd
+	m.diffInput, cmd = m.diffInput.Update(msg)
+	return m, cmd
+}
+
+// startDiff compares the open database with the one at path in the background and shows the diff panel.
+func (m *Model) startDiff(path string) tea.Cmd {
+	m.diffView = diffPanel{
+		seq:     m.diffView.seq + 1,
+		other:   path,
+		running: true,
+	}
+	m.state = DiffPanel
+	return compareDatabases(m.driver, path, m.backgroundOptions(), m.diffView.seq)
+}
+
+func (m Model) diffDone(msg diffDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.diffView.seq {
+		return m, nil
+	}
+	m.diffView.running = false
+	m.diffView.err = msg.err
+	if msg.err != nil {
+		m.Error(fmt.Sprintf("Compare failed: %v", msg.err))
+		return m, nil
+	}
+	m.diffView.result = &msg.result
+	if msg.result.Equal() {
+		m.Success(fmt.Sprintf("%v holds the same documents", filepath.Base(m.diffView.other)))
+	}
+	return m, m.ClearInfoAfter("3s")
+}
+
+// lines lists the collections of the diff, each followed by the documents that differ.
+func (p diffPanel) lines() []diffLine {
+	if p.result == nil {
+		return nil
+	}
+	var lines []diffLine
+	for c, coll := range p.result.Collections {
+		lines = append(lines, diffLine{collection: c, doc: -1})
+		for d := range coll.Documents {
+			lines = append(lines, diffLine{collection: c, doc: d})
+		}
+	}
+	return lines
+}
+
+// stepDocument moves the cursor delta documents further, skipping the collection lines.
+func (p *diffPanel) stepDocument(delta int) {
+	lines := p.lines()
+	for i := p.cursor + delta; i >= 0 && i < len(lines); i += delta {
+		if lines[i].doc >= 0 {
+			p.cursor = i
+			p.offset = 0
+			return
+		}
+	}
+}
+
+func (m Model) updateDiffPanel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := &m.diffView
+	lines := p.lines()
+	page := max(1, m.window.height-14)
+
+	if p.document {
+		switch {
+		case key.Matches(msg, m.keys.Escape, m.keys.Enter):
+			p.document = false
+		case key.Matches(msg, m.keys.Quit):
+			return m, tea.Quit
+		case key.Matches(msg, m.keys.Up):
+			p.offset = max(0, p.offset-1)
+		case key.Matches(msg, m.keys.Down):
+			p.offset++
+		case key.Matches(msg, m.keys.PgUp):
+			p.offset = max(0, p.offset-page)
+		case key.Matches(msg, m.keys.PgDn):
+			p.offset += page
+		case key.Matches(msg, m.keys.Next):
+			p.stepDocument(1)
+		case key.Matches(msg, m.keys.Prev):
+			p.stepDocument(-1)
+		}
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		m.state = Normal
+		// a comparison still running is dropped
+		p.seq++
+	case key.Matches(msg, m.keys.Diff):
+		return m.showDiffPrompt()
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Up):
+		p.cursor = max(0, p.cursor-1)
+	case key.Matches(msg, m.keys.Down):
+		p.cursor = min(len(lines)-1, p.cursor+1)
+	case key.Matches(msg, m.keys.PgUp):
+		p.cursor = max(0, p.cursor-page)
+	case key.Matches(msg, m.keys.PgDn):
+		p.cursor = min(len(lines)-1, p.cursor+page)
+	case key.Matches(msg, m.keys.Enter):
+		if p.cursor >= 0 && p.cursor < len(lines) && lines[p.cursor].doc >= 0 {
+			p.document = true
+			p.offset = 0
+		}
+	}
+	return m, nil
+}
+
+func (m Model) RenderDiffPanel() string {
+	p := m.diffView
+	title := fmt.Sprintf("Comparing %v with %v", logoStyle.Copy().PaddingLeft(0).Render(filepath.Base(m.DatabaseFile)),
+		logoStyle.Copy().PaddingLeft(0).Render(filepath.Base(p.other)))
+	switch {
+	case p.running:
+		return fmt.Sprintf("%v\n\ncomparing...\n\n[esc] cancel", title)
+	case p.err != nil:
+		return fmt.Sprintf("%v\n\n%v\n\n[D] compare with another database    [esc] close", title, errorStyle.Render(p.err.Error()))
+	}
+	lines := p.lines()
+	if p.document && p.cursor < len(lines) {
+		return m.renderDiffDocument(lines[p.cursor])
+	}
+
+	height := max(1, m.window.height-14)
+	start := max(0, min(p.cursor-height/2, len(lines)-height))
+	end := min(len(lines), start+height)
+	var b strings.Builder
+	for i := start; i < end; i++ {
+		line := lines[i]
+		coll := p.result.Collections[line.collection]
+		var text string
+		if line.doc < 0 {
+			text = fmt.Sprintf("%v  %v", coll.Name, renderCollectionDiff(coll))
+		} else {
+			doc := coll.Documents[line.doc]
+			text = "  " + diffMarker(doc.Status) + " " + doc.Key
+			if doc.Status == dbdiff.Modified {
+				text += treePunctStyle.Render(fmt.Sprintf("  %v field(s)", len(doc.Fields)))
+			}
+		}
+		if i == p.cursor {
+			text = accentStyle.Copy().Padding(0).Render(text)
+		}
+		b.WriteString(text + "\n")
+	}
+	return fmt.Sprintf("%v\n\n%v\n[enter] show side by side    [D] compare with another database    [esc] close", title, b.String())
+}
+
+// renderCollectionDiff sums up how a collection differs.
+func renderCollectionDiff(c dbdiff.Collection) string {
+	switch c.Status {
+	case dbdiff.Added:
+		return diffAddStyle.Render(fmt.Sprintf("only in the other database, %v document(s)", c.Added))
+	case dbdiff.Removed:
+		return diffRemoveStyle.Render(fmt.Sprintf("only in this database, %v document(s)", c.Removed))
+	case dbdiff.Same:
+		return treePunctStyle.Render(fmt.Sprintf("no changes, %v document(s)", c.Unchanged))
+	}
+	return fmt.Sprintf("%v %v %v %v",
+		diffAddStyle.Render(fmt.Sprintf("+%v", c.Added)),
+		diffRemoveStyle.Render(fmt.Sprintf("-%v", c.Removed)),
+		diffModifiedStyle.Render(fmt.Sprintf("~%v", c.Modified)),
+		treePunctStyle.Render(fmt.Sprintf("%v unchanged", c.Unchanged)))
+}
+
+func diffMarker(status dbdiff.Status) string {
+	switch status {
+	case dbdiff.Added:
+		return diffAddStyle.Render("+")
+	case dbdiff.Removed:
+		return diffRemoveStyle.Render("-")
+	}
+	return diffModifiedStyle.Render("~")
+}
+
+// renderDiffDocument lists the fields that changed and shows both versions of the document side by side.
+func (m Model) renderDiffDocument(line diffLine) string {
+	p := m.diffView
+	coll := p.result.Collections[line.collection]
+	doc := coll.Documents[line.doc]
+	title := fmt.Sprintf("%v %v in %v", diffMarker(doc.Status), logoStyle.Copy().PaddingLeft(0).Render(doc.Key), coll.Name)
+
+	var rows []string
+	for _, f := range doc.Fields {
+		switch f.Status {
+		case dbdiff.Added:
+			rows = append(rows, diffAddStyle.Render(fmt.Sprintf("+ %v: %v", f.Path, dbdiff.Format(f.New))))
+		case dbdiff.Removed:
+			rows = append(rows, diffRemoveStyle.Render(fmt.Sprintf("- %v: %v", f.Path, dbdiff.Format(f.Old))))
+		default:
+			rows = append(rows, diffModifiedStyle.Render(fmt.Sprintf("~ %v: %v → %v", f.Path, dbdiff.Format(f.Old), dbdiff.Format(f.New))))
+		}
+	}
+	if len(rows) > 0 {
+		rows = append(rows, "")
+	}
+	width := max(20, m.window.width-6)
+	half := (width - 3) / 2
+	rows = append(rows, padCell(filepath.Base(m.DatabaseFile), half, logoStyle.Copy().PaddingLeft(0))+" │ "+
+		padCell(filepath.Base(p.other), half, logoStyle.Copy().PaddingLeft(0)))
+	rows = append(rows, sideBySide(documentLines(doc.Old), documentLines(doc.New), half)...)
+
+	height := max(1, m.window.height-14)
+	offset := max(0, min(p.offset, len(rows)-height))
+	end := min(len(rows), offset+height)
+	return fmt.Sprintf("%v\n\n%v\n\n[up/down] scroll    [n/p] next/previous document    [esc] back to the list",
+		title, strings.Join(rows[offset:end], "\n"))
+}
+
+// documentLines is a document as indented JSON, one line per entry, nil has no lines.
+func documentLines(doc map[string]any) []string {
+	if doc == nil {
+		return nil
+	}
+	r, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return strings.Split(string(r), "\n")
+}
+
+// sideBySide puts a line diff of a and b into two columns, removed lines are paired up with
+// the added lines that replace them.
+func sideBySide(a, b []string, width int) []string {
+	var rows, removed, added []string
+	flush := func() {
+		for i := 0; i < max(len(removed), len(added)); i++ {
+			left, right := padCell("", width, lipgloss.NewStyle()), padCell("", width, lipgloss.NewStyle())
+			if i < len(removed) {
+				left = padCell(removed[i], width, diffRemoveStyle)
+			}
+			if i < len(added) {
+				right = padCell(added[i], width, diffAddStyle)
+			}
+			rows = append(rows, left+" │ "+right)
+		}
+		removed, added = nil, nil
+	}
+	for _, line := range diffLines(a, b) {
+		switch {
+		case strings.HasPrefix(line, "- "):
+			removed = append(removed, line[2:])
+		case strings.HasPrefix(line, "+ "):
+			added = append(added, line[2:])
+		default:
+			flush()
+			rows = append(rows, padCell(line[2:], width, lipgloss.NewStyle())+" │ "+padCell(line[2:], width, lipgloss.NewStyle()))
+		}
+	}
+	flush()
+	return rows
+}
+
+// padCell cuts or pads text to width before styling it.
+func padCell(text string, width int, style lipgloss.Style) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		runes = append(runes[:max(0, width-1)], '…')
+	}
+	return style.Render(string(runes) + strings.Repeat(" ", max(0, width-len(runes))))
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"io"
+	"sort"
+	"strings"
+)
+
+// docField is one line of the document view, key is the name the value is stored under in the document.
+type docField struct {
+	name  string
+	key   string
+	value any
+}
+
+// recordFields lists the fields of the shown document, the bbolt key first,
+// then the known columns and whatever else the document holds.
+func (m Model) recordFields() []docField {
+	doc := m.record
+	fields := []docField{{KEY_COLUMN, KEY_COLUMN, string(m.recordKey)}}
+	seen := map[string]bool{}
+	for _, colAliases := range m.fields {
+		colname := colAliases[len(colAliases)-1]
+		f := docField{name: colname, key: colname}
+		found := false
+		for _, alias := range colAliases {
+			seen[alias] = true
+			if val, ok := doc[alias]; ok && !found {
+				f.key, f.value, found = alias, val, true
+			}
+		}
+		fields = append(fields, f)
+	}
+	var extra []string
+	for k := range doc {
+		if !seen[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	for _, k := range extra {
+		fields = append(fields, docField{k, k, doc[k]})
+	}
+	return fields
+}
+
+// editSession is the field being edited, value is the parsed new value once it is previewed.
+type editSession struct {
+	collection string
+	key        []byte
+	field      docField
+	value      any
+	diff       []string
+	preview    bool
+}
+
+// beginEdit opens the editor for the top level field the tree cursor of the document view is in.
+func (m Model) beginEdit() (tea.Model, tea.Cmd) {
+	if !m.showRecord || m.record == nil {
+		return m, nil
+	}
+	if m.driver.ReadOnly {
+		m.Error(fmt.Sprintf("Can't edit, %v", store.ErrReadOnly))
+		return m, nil
+	}
+	node, ok := m.treeNode()
+	if !ok {
+		return m, nil
+	}
+	field := m.recordFields()[node.field]
+	if field.name == KEY_COLUMN {
+		m.Error("The key of a document can't be edited")
+		return m, nil
+	}
+
+	value, err := json.MarshalIndent(field.value, "", "  ")
+	if err != nil {
+		m.Error(fmt.Sprintf("Can't edit %v: %v", field.name, err))
+		return m, nil
+	}
+	m.editing = editSession{
+		collection: m.collections[m.activeCollection],
+		key:        m.recordKey,
+		field:      field,
+	}
+	m.editor = textarea.New()
+	m.editor.CharLimit = 0
+	m.editor.SetWidth(m.window.width - 6)
+	m.editor.SetHeight(m.window.height - 14)
+	m.editor.SetValue(string(value))
+	m.state = EditField
+	return m, m.editor.Focus()
+}
+
+func (m Model) updateEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editing.preview {
+		switch msg.String() {
+		case "y", "enter":
+			return m.saveEdit()
+		case "n", "backspace":
+			m.editing.preview = false
+			return m, m.editor.Focus()
+		case "esc":
+			return m.cancelEdit()
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		return m.cancelEdit()
+	case "ctrl+s":
+		value, err := parseJSON(m.editor.Value())
+		if err != nil {
+			m.Error(fmt.Sprintf("Invalid JSON: %v", err))
+			return m, nil
+		}
+		before, _ := json.MarshalIndent(m.editing.field.value, "", "  ")
+		after, _ := json.MarshalIndent(value, "", "  ")
+		if bytes.Equal(before, after) {
+			m.Info(fmt.Sprintf("%v is unchanged", m.editing.field.name))
+			return m.cancelEdit()
+		}
+		m.editing.value = value
+		m.editing.diff = diffLines(strings.Split(string(before), "\n"), strings.Split(string(after), "\n"))
+		m.editing.preview = true
+		m.editor.Blur()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	return m, cmd
+}
+
+func (m Model) cancelEdit() (tea.Model, tea.Cmd) {
+	m.state = Normal
+	m.editor.Blur()
+	m.editing = editSession{}
+	return m, nil
+}
+
+// saveEdit writes the new value into the stored document and refreshes its row.
+func (m Model) saveEdit() (tea.Model, tea.Cmd) {
+	edit := m.editing
+	err := store.Update[kmap](m.driver, edit.collection, edit.key, func(doc *kmap) error {
+		if *doc == nil {
+			*doc = kmap{}
+		}
+		(*doc)[edit.field.key] = edit.value
+		return nil
+	})
+	m.state = Normal
+	m.editing = editSession{}
+	if err != nil {
+		m.Error(fmt.Sprintf("Saving %v failed: %v", edit.field.name, err))
+		return m, nil
+	}
+
+	m.refreshRow(edit.key)
+	m.Success(fmt.Sprintf("Saved %v of %v", edit.field.name, string(edit.key)))
+	return m, tea.Batch(m.resort(), m.ClearInfoAfter("3s"))
+}
+
+// refreshRow reads the document stored under key again and updates its row in the table.
+func (m *Model) refreshRow(key []byte) {
+	for i, k := range m.rowKeys {
+		if !bytes.Equal(k, key) {
+			continue
+		}
+		doc, err := m.lookup(i)
+		if err != nil {
+			m.Error(fmt.Sprintf("Failed to reload %v: %v", string(key), err))
+			return
+		}
+		m.rowData[i], m.cleanRowData[i] = buildRow(m.columns, m.flatten > 0, key, doc)
+		m.rebuildTable()
+		break
+	}
+	m.recordKey = nil
+}
+
+// parseJSON decodes a single JSON value, numbers are kept as written so large integers survive.
+func parseJSON(s string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after the value at offset %v", dec.InputOffset())
+	}
+	return value, nil
+}
+
+var (
+	diffAddStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#55ff55"))
+	diffRemoveStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5555"))
+)
+
+// diffLines returns a line diff of a and b, every line is prefixed with "  ", "- " or "+ ".
+func diffLines(a, b []string) []string {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
+
+func renderDiff(lines []string) string {
+	var b strings.Builder
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+ "):
+			line = diffAddStyle.Render(line)
+		case strings.HasPrefix(line, "- "):
+			line = diffRemoveStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
+
+func (m Model) RenderEditor() string {
+	edit := m.editing
+	title := fmt.Sprintf("Editing %v of %v in %v", logoStyle.Copy().PaddingLeft(0).Render(edit.field.name), string(edit.key), edit.collection)
+	if edit.preview {
+		return fmt.Sprintf("%v\n\n%v\n[y] save    [n] keep editing    [esc] cancel", title, renderDiff(edit.diff))
+	}
+	return fmt.Sprintf("%v\n\n%v\n\n[ctrl+s] preview changes    [esc] cancel", title, m.editor.View())
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want []string
+	}{
+		{"", "", nil},
+		{"a b c", "a b c", []string{"  a", "  b", "  c"}},
+		{"", "a b", []string{"+ a", "+ b"}},
+		{"a b", "", []string{"- a", "- b"}},
+		// a changed line is removed before its replacement is added
+		{"a b c", "a x c", []string{"  a", "- b", "+ x", "  c"}},
+		// the longest common subsequence is kept, not the first match
+		{"x a b c", "a b c x", []string{"- x", "  a", "  b", "  c", "+ x"}},
+		{"a b a b", "b a b", []string{"- a", "  b", "  a", "  b"}},
+	}
+	for _, tt := range tests {
+		got := diffLines(strings.Fields(tt.a), strings.Fields(tt.b))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q -> %q: got %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
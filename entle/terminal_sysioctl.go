@@ -1,7 +1,7 @@
 //go:build !windows && !plan9 && !solaris
 // +build !windows,!plan9,!solaris
 
-package goterm
+package entle
 
 import (
 	"errors"
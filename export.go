@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nokusukun/bingo"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// transferProgressMsg reports how far an export or import running in the background got.
+type transferProgressMsg struct {
+	done int64
+}
+
+// exportDoneMsg is sent once an export finished or failed.
+type exportDoneMsg struct {
+	path    string
+	written int
+	err     error
+}
+
+// transfer tracks the export or import running in the background, done and total
+// count documents for an export and bytes read for an import.
+type transfer struct {
+	verb     string
+	path     string
+	done     int64
+	total    int64
+	progress chan transferProgressMsg
+	bar      progress.Model
+}
+
+func newTransfer(verb, path string, total int64) *transfer {
+	return &transfer{
+		verb:     verb,
+		path:     path,
+		total:    total,
+		progress: make(chan transferProgressMsg, 1),
+		bar:      progress.New(progress.WithSolidFill("#7ac0f1"), progress.WithoutPercentage(), progress.WithWidth(20)),
+	}
+}
+
+// report passes progress on without ever blocking the transfer, updates are dropped while the UI is busy.
+func (t *transfer) report(done int64) {
+	select {
+	case t.progress <- transferProgressMsg{done: done}:
+	default:
+	}
+}
+
+func waitTransferProgress(ch chan transferProgressMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func (m Model) transferProgress(msg transferProgressMsg) (tea.Model, tea.Cmd) {
+	if m.transfer == nil {
+		return m, nil
+	}
+	m.transfer.done = msg.done
+	return m, waitTransferProgress(m.transfer.progress)
+}
+
+func (m Model) RenderTransfer() string {
+	ratio := 0.0
+	if m.transfer.total > 0 {
+		ratio = min(1, float64(m.transfer.done)/float64(m.transfer.total))
+	}
+	return fmt.Sprintf("%v %v %v %.0f%%", m.transfer.verb, filepath.Base(m.transfer.path), m.transfer.bar.ViewAs(ratio), ratio*100)
+}
+
+// exportFormat picks the output format from the file extension, JSON is the default.
+func exportFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return "csv"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	}
+	return "json"
+}
+
+func (m Model) showExportPrompt() (tea.Model, tea.Cmd) {
+	if m.driver == nil || len(m.collections) == 0 {
+		return m, nil
+	}
+	if m.transfer != nil {
+		m.Error(fmt.Sprintf("Still %v %v", m.transfer.verb, m.transfer.path))
+		return m, nil
+	}
+	m.exportInput = textinput.New()
+	m.exportInput.Prompt = "export to: "
+	m.exportInput.SetValue(m.collections[m.activeCollection] + ".json")
+	m.exportInput.CursorEnd()
+	m.state = ExportPrompt
+	return m, m.exportInput.Focus()
+}
+
+func (m Model) updateExportPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		m.state = Normal
+		m.exportInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		path := strings.TrimSpace(m.exportInput.Value())
+		if path == "" {
+			return m, nil
+		}
+		m.state = Normal
+		m.exportInput.Blur()
+		return m.startExport(path)
+	}
+	var cmd tea.Cmd
+	m.exportInput, cmd = m.exportInput.Update(msg)
+	return m, cmd
+}
+
+// startExport writes the rows of the current view to path in the background, the whole collection
+// when it isn't filtered. Sorted views keep their order, otherwise documents are written newest first.
+func (m Model) startExport(path string) (tea.Model, tea.Cmd) {
+	db, collection := m.driver, m.collections[m.activeCollection]
+	var columns [][]string
+	for _, col := range m.columns {
+		// every document carries its key anyway
+		if !isKeyColumn(col) {
+			columns = append(columns, col)
+		}
+	}
+	q := bingo.Query[kmap]{Filter: m.rowFilter()}
+	total := m.rowCount
+	if (m.loadedFirst && m.loadedAll) || total < 0 {
+		total = len(m.rowData)
+	}
+	if len(m.activeSort()) > 0 {
+		order := m.sortedKeys()
+		if order == nil || m.sortScan != nil {
+			m.Error("Still sorting, export once the sort is done")
+			return m, nil
+		}
+		// the sorted order already holds the keys of every row matching the query
+		q, total = bingo.Query[kmap]{Keys: order}, len(order)
+	}
+	m.transfer = newTransfer("exporting", path, int64(total))
+	t := m.transfer
+
+	run := func() tea.Msg {
+		defer close(t.progress)
+		written, err := exportDocuments(db, collection, columns, q, path, func(written int) {
+			t.report(int64(written))
+		})
+		return exportDoneMsg{path: path, written: written, err: err}
+	}
+	m.Info(fmt.Sprintf("Exporting %v to %v", collection, path))
+	return m, tea.Batch(run, waitTransferProgress(t.progress))
+}
+
+func (m Model) exportDone(msg exportDoneMsg) (tea.Model, tea.Cmd) {
+	m.transfer = nil
+	if msg.err != nil {
+		m.Error(fmt.Sprintf("Export failed: %v", msg.err))
+		return m, nil
+	}
+	abs, err := filepath.Abs(msg.path)
+	if err != nil {
+		abs = msg.path
+	}
+	m.Success(fmt.Sprintf("Exported %v document(s) to %v", msg.written, abs))
+	return m, m.ClearInfoAfter("5s")
+}
+
+// exportDocuments writes every document matching q to path, report is called every few hundred documents.
+// Documents carry their bbolt key under KEY_COLUMN so an export can be imported again. A file that
+// was created but not written to the end is removed, a path that couldn't be created is left alone.
+func exportDocuments(db *store.DB, collection string, columns [][]string, q bingo.Query[kmap], path string, report func(written int)) (written int, err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = f.Close()
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+	w := bufio.NewWriter(f)
+
+	format := exportFormat(path)
+	var csvWriter *csv.Writer
+	switch format {
+	case "json":
+		_, err = w.WriteString("[\n")
+	case "csv":
+		csvWriter = csv.NewWriter(w)
+		header := []string{KEY_COLUMN}
+		for _, col := range columns {
+			header = append(header, col[0])
+		}
+		err = csvWriter.Write(header)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	err = store.Find(db, collection, q, func(key []byte, docPtr *kmap) error {
+		doc := *docPtr
+		switch format {
+		case "csv":
+			record := []string{string(key)}
+			for _, col := range columns {
+				record = append(record, csvValue(firstAlias(doc, col)))
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return err
+			}
+		default:
+			out := make(map[string]any, len(doc)+1)
+			for k, v := range doc {
+				out[k] = v
+			}
+			out[KEY_COLUMN] = string(key)
+			data, err := json.Marshal(out)
+			if err != nil {
+				return err
+			}
+			if format == "json" && written > 0 {
+				data = append([]byte(",\n"), data...)
+			}
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+			if format == "ndjson" {
+				if err := w.WriteByte('\n'); err != nil {
+					return err
+				}
+			}
+		}
+		written++
+		if written%250 == 0 {
+			report(written)
+		}
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+
+	switch format {
+	case "json":
+		_, err = w.WriteString("\n]\n")
+	case "csv":
+		csvWriter.Flush()
+		err = csvWriter.Error()
+	}
+	if err != nil {
+		return written, err
+	}
+	if err := w.Flush(); err != nil {
+		return written, err
+	}
+	return written, f.Close()
+}
+
+// firstAlias returns the value of the first alias of the column present in the document.
+func firstAlias(doc kmap, aliases []string) any {
+	v, _ := lookupColumn(doc, aliases)
+	return v
+}
+
+// csvValue writes scalars as they are and nested values as JSON, missing values stay empty.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"github.com/nokusukun/bingo"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// an export that can't create its file leaves whatever is at the path alone
+func TestExportKeepsPathItCouldNotCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out.json")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := exportDocuments(nil, "users", nil, bingo.Query[kmap]{}, dir, func(int) {}); err == nil {
+		t.Fatal("exporting to a directory succeeded")
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("the directory is gone: %v", err)
+	}
+}
+
+// an export that fails after creating its file doesn't leave a partial file behind
+func TestExportRemovesPartialFile(t *testing.T) {
+	db, err := store.Open(filepath.Join(t.TempDir(), "test.db"), store.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	path := filepath.Join(t.TempDir(), "out.json")
+	if _, err := exportDocuments(db, "missing", nil, bingo.Query[kmap]{}, path, func(int) {}); err == nil {
+		t.Fatal("exporting a missing collection succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("the partial export is still there: %v", err)
+	}
+}
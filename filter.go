@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bingoviewer/query"
+	"bingoviewer/store"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nokusukun/bingo"
+	"strings"
+)
+
+// MAX_QUERY_HISTORY is how many past queries are remembered per collection.
+const MAX_QUERY_HISTORY = 50
+
+func newQueryInput() textinput.Model {
+	input := textinput.New()
+	input.Prompt = "/ "
+	input.Placeholder = `age > 30 && status == "active" && tags contains "beta"`
+	return input
+}
+
+// activeQuery returns the query filtering the active collection, if any.
+func (m Model) activeQuery() *query.Query {
+	if len(m.collections) == 0 {
+		return nil
+	}
+	return m.queries[m.collections[m.activeCollection]]
+}
+
+// rowFilter turns the active query into the filter used when loading rows, nil when there is none
+// so rows can be skipped without decoding them.
+func (m Model) rowFilter() func(doc kmap) bool {
+	q := m.activeQuery()
+	if q == nil {
+		return nil
+	}
+	return func(doc kmap) bool {
+		return q.Match(doc)
+	}
+}
+
+// countRows counts the documents of the collection in the background, a filtered
+// collection has to be decoded completely while an unfiltered one only needs the bucket stats.
+func countRows(db *store.DB, collection string, q *query.Query, seq int) tea.Cmd {
+	return func() tea.Msg {
+		if q == nil {
+			count, err := db.Count(collection)
+			return rowCountMsg{seq: seq, count: count, err: err}
+		}
+		count := 0
+		err := store.Find(db, collection, bingo.Query[kmap]{
+			Filter: func(doc kmap) bool {
+				return q.Match(doc)
+			},
+		}, func(_ []byte, _ *kmap) error {
+			count++
+			return nil
+		})
+		return rowCountMsg{seq: seq, count: count, err: err}
+	}
+}
+
+// recount counts the documents of the active collection again in the background.
+func (m *Model) recount() tea.Cmd {
+	m.countSeq++
+	return countRows(m.driver, m.collections[m.activeCollection], m.activeQuery(), m.countSeq)
+}
+
+func (m Model) showQueryBar() (tea.Model, tea.Cmd) {
+	if m.DatabaseFile == "" || len(m.collections) == 0 {
+		return m, nil
+	}
+	m.state = QueryBar
+	m.queryInput.SetValue("")
+	if q := m.activeQuery(); q != nil {
+		m.queryInput.SetValue(q.Source)
+	}
+	m.queryInput.CursorEnd()
+	m.historyPos = len(m.queryHistory[m.collections[m.activeCollection]])
+	return m, m.queryInput.Focus()
+}
+
+func (m Model) updateQueryBar(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	collection := m.collections[m.activeCollection]
+	history := m.queryHistory[collection]
+
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		m.state = Normal
+		m.queryInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		return m.applyQuery(strings.TrimSpace(m.queryInput.Value()))
+	case msg.String() == "up":
+		if m.historyPos > 0 {
+			m.historyPos--
+			m.queryInput.SetValue(history[m.historyPos])
+			m.queryInput.CursorEnd()
+		}
+		return m, nil
+	case msg.String() == "down":
+		if m.historyPos < len(history) {
+			m.historyPos++
+		}
+		if m.historyPos == len(history) {
+			m.queryInput.SetValue("")
+		} else {
+			m.queryInput.SetValue(history[m.historyPos])
+		}
+		m.queryInput.CursorEnd()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.queryInput, cmd = m.queryInput.Update(msg)
+	return m, cmd
+}
+
+// applyQuery filters the active collection with src, an empty query shows every row again.
+func (m Model) applyQuery(src string) (tea.Model, tea.Cmd) {
+	collection := m.collections[m.activeCollection]
+	if src == "" {
+		delete(m.queries, collection)
+	} else {
+		q, err := query.Compile(src)
+		if err != nil {
+			m.Error(fmt.Sprintf("Invalid query: %v", err))
+			return m, nil
+		}
+		m.queries[collection] = q
+		m.rememberQuery(collection, src)
+	}
+
+	m.state = Normal
+	m.queryInput.Blur()
+	return m, m.loadCollection()
+}
+
+func (m *Model) rememberQuery(collection, src string) {
+	history := m.queryHistory[collection]
+	for i, past := range history {
+		if past == src {
+			history = append(history[:i], history[i+1:]...)
+			break
+		}
+	}
+	history = append(history, src)
+	if len(history) > MAX_QUERY_HISTORY {
+		history = history[len(history)-MAX_QUERY_HISTORY:]
+	}
+	m.queryHistory[collection] = history
+}
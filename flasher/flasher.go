@@ -18,9 +18,16 @@ type FlashEvent struct {
 	Type any
 }
 type FlashMessage string
+type FlashQuestion string
 type FlashConfirmMsg struct{}
 type FlashInterruptMsg struct{}
 
+// FlashAnswerMsg is sent once a question flash is answered.
+type FlashAnswerMsg struct {
+	Id  string
+	Yes bool
+}
+
 func SendFlash(id, m string) tea.Cmd {
 	return func() tea.Msg {
 		return FlashEvent{
@@ -30,6 +37,25 @@ func SendFlash(id, m string) tea.Cmd {
 	}
 }
 
+// AskFlash shows a yes/no question, the answer comes back as a FlashAnswerMsg.
+func AskFlash(id, q string) tea.Cmd {
+	return func() tea.Msg {
+		return FlashEvent{
+			Id:   id,
+			Type: FlashQuestion(q),
+		}
+	}
+}
+
+func answer(id string, yes bool) tea.Cmd {
+	return func() tea.Msg {
+		return FlashAnswerMsg{
+			Id:  id,
+			Yes: yes,
+		}
+	}
+}
+
 func ConfirmFlash(id string) tea.Cmd {
 	return func() tea.Msg {
 		return FlashEvent{
@@ -49,10 +75,11 @@ func interrupt(id string) tea.Cmd {
 }
 
 type Model struct {
-	Id      string
-	Message string
-	Active  bool
-	Style   lipgloss.Style
+	Id       string
+	Message  string
+	Active   bool
+	Question bool
+	Style    lipgloss.Style
 }
 
 func DefaultStyle() lipgloss.Style {
@@ -99,6 +126,11 @@ func (f Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		case FlashMessage:
 			f.Message = string(event)
 			f.Active = true
+			f.Question = false
+		case FlashQuestion:
+			f.Message = string(event)
+			f.Active = true
+			f.Question = true
 		case FlashConfirmMsg:
 			f.Active = false
 		case FlashInterruptMsg:
@@ -107,6 +139,18 @@ func (f Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 			}
 		}
 	case tea.KeyMsg:
+		if f.Active && f.Question {
+			// only an explicit y says yes, enter doesn't so a stray key press can't confirm
+			switch msg.String() {
+			case "y":
+				f.Active = false
+				return f, answer(f.Id, true)
+			case "n", "enter", "esc", "q":
+				f.Active = false
+				return f, answer(f.Id, false)
+			}
+			return f, nil
+		}
 		switch msg.String() {
 		case "enter", "esc", "q":
 			f.Active = false
@@ -125,7 +169,11 @@ func (f Model) View() string {
 		return ""
 	}
 
-	block := f.Style.Render(f.Message)
+	message := f.Message
+	if f.Question {
+		message += "\n\n[y] yes    [n] no"
+	}
+	block := f.Style.Render(message)
 	block = lipgloss.Place(entle.Width(), entle.Height(), lipgloss.Center, lipgloss.Center, block)
 	//block = lipgloss.PlaceVertical(entle.Height(), lipgloss.Center, block)
 
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nokusukun/bingo"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// FLATTEN_MAX is the deepest level F steps through before it turns flattening off again.
+const FLATTEN_MAX = 3
+
+// flattening tells how the fields are split into columns, keyed by the first alias of each field.
+// A field holds the dotted paths of its nested fields, or nil when it is kept whole because some
+// document holds anything but an object in it. Fields no document had a value for are left out.
+type flattening map[string][]string
+
+// flattenFields finds the nested fields of docs down to depth levels.
+func flattenFields(fields [][]string, docs []kmap, depth int) flattening {
+	flat := flattening{}
+	for _, aliases := range fields {
+		var values []any
+		for _, doc := range docs {
+			if v, ok := lookupColumn(doc, aliases); ok && v != nil {
+				values = append(values, v)
+			}
+		}
+		if len(values) > 0 {
+			flat[aliases[0]] = nestedPaths(values, depth)
+		}
+	}
+	return flat
+}
+
+// merge adds the nested fields found in other documents and reports whether the columns changed.
+// A field is kept whole once any document holds something else in it, and so is a nested field.
+func (f flattening) merge(other flattening) bool {
+	changed := false
+	for field, paths := range other {
+		current, seen := f[field]
+		switch {
+		case !seen:
+			f[field] = paths
+		case current == nil:
+			continue
+		case paths == nil:
+			f[field] = nil
+		default:
+			merged := mergePaths(current, paths)
+			if slices.Equal(merged, current) {
+				continue
+			}
+			f[field] = merged
+		}
+		changed = true
+	}
+	return changed
+}
+
+// mergePaths joins two lists of nested paths in the order nestedPaths lists them, a path is
+// dropped when a path above it is in either list.
+func mergePaths(a, b []string) []string {
+	all := map[string]bool{}
+	for _, path := range append(append([]string{}, a...), b...) {
+		all[path] = true
+	}
+	var paths []string
+	for path := range all {
+		whole := false
+		for i := range path {
+			if path[i] == '.' && all[path[:i]] {
+				whole = true
+				break
+			}
+		}
+		if !whole {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return slices.Compare(strings.Split(paths[i], "."), strings.Split(paths[j], ".")) < 0
+	})
+	return paths
+}
+
+// flattenColumns replaces the fields holding objects with a column per nested field, named
+// parent.child, parents maps every column back to the field it came from.
+func flattenColumns(fields [][]string, flat flattening) (columns [][]string, parents []int) {
+	for i, aliases := range fields {
+		paths := flat[aliases[0]]
+		if paths == nil {
+			columns = append(columns, aliases)
+			parents = append(parents, i)
+			continue
+		}
+		for _, path := range paths {
+			column := make([]string, len(aliases))
+			for j, alias := range aliases {
+				column[j] = alias + "." + path
+			}
+			columns = append(columns, column)
+			parents = append(parents, i)
+		}
+	}
+	return columns, parents
+}
+
+// nestedPaths lists the dotted paths below values down to depth levels, nil unless every value is an object holding something.
+func nestedPaths(values []any, depth int) []string {
+	if depth <= 0 || len(values) == 0 {
+		return nil
+	}
+	var keys []string
+	children := map[string][]any{}
+	for _, v := range values {
+		obj, ok := v.(map[string]any)
+		if !ok || len(obj) == 0 {
+			return nil
+		}
+		for k, child := range obj {
+			if _, seen := children[k]; !seen {
+				keys = append(keys, k)
+				children[k] = nil
+			}
+			if child != nil {
+				children[k] = append(children[k], child)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	var paths []string
+	for _, k := range keys {
+		below := nestedPaths(children[k], depth-1)
+		if below == nil {
+			paths = append(paths, k)
+			continue
+		}
+		for _, path := range below {
+			paths = append(paths, k+"."+path)
+		}
+	}
+	return paths
+}
+
+// lookupColumn returns the value of the first alias the document holds, dotted aliases of flattened
+// columns are followed into nested objects.
+func lookupColumn(doc kmap, aliases []string) (any, bool) {
+	for _, alias := range aliases {
+		if v, ok := doc[alias]; ok {
+			return v, true
+		}
+		parts := strings.Split(alias, ".")
+		if len(parts) == 1 {
+			continue
+		}
+		var current any = map[string]any(doc)
+		found := true
+		for _, part := range parts {
+			obj, ok := current.(map[string]any)
+			if !ok {
+				found = false
+				break
+			}
+			if current, ok = obj[part]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return current, true
+		}
+	}
+	return nil, false
+}
+
+// compactCell is how flattened tables show the arrays and objects left in a cell.
+func compactCell(v any) (string, bool) {
+	switch v := v.(type) {
+	case []any:
+		if len(v) == 1 {
+			return "[1 item]", true
+		}
+		return fmt.Sprintf("[%v items]", len(v)), true
+	case map[string]any:
+		if len(v) == 1 {
+			return "{1 key}", true
+		}
+		return fmt.Sprintf("{%v keys}", len(v)), true
+	}
+	return "", false
+}
+
+// toggleKeyColumn shows or hides the column of the document keys.
+func (m *Model) toggleKeyColumn() tea.Cmd {
+	if m.driver == nil || len(m.collections) == 0 {
+		return nil
+	}
+	m.showKey = !m.showKey
+	current, _ := m.cursorKey()
+	if err := m.layoutColumns(); err != nil {
+		m.Error(fmt.Sprintf("Failed to get columns: %v", err))
+		return nil
+	}
+	switch {
+	case m.showKey:
+		m.Info(fmt.Sprintf("Showing the keys under %v", KEY_COLUMN))
+	case len(m.pruneSort()) > 0:
+		m.Info(fmt.Sprintf("%v is hidden, the sort by it was cleared", KEY_COLUMN))
+	default:
+		m.Info(fmt.Sprintf("%v is hidden", KEY_COLUMN))
+	}
+	return tea.Batch(m.ensureSort(), m.reloadRows(current))
+}
+
+// sampleDocs reads the documents the first page of the table is built from.
+func sampleDocs(db *store.DB, collection string, filter func(doc kmap) bool) ([]kmap, error) {
+	var docs []kmap
+	err := store.Find(db, collection, bingo.Query[kmap]{Count: PAGE_SIZE, Filter: filter}, func(_ []byte, doc *kmap) error {
+		docs = append(docs, *doc)
+		return nil
+	})
+	return docs, err
+}
+
+// toggleFlatten steps to the next flatten depth and lays the table out again. Sort keys of columns
+// the new layout doesn't have are cleared, the others are kept.
+func (m *Model) toggleFlatten() tea.Cmd {
+	if m.driver == nil || len(m.collections) == 0 {
+		return nil
+	}
+	m.flatten++
+	if m.flatten > FLATTEN_MAX {
+		m.flatten = 0
+	}
+	current, _ := m.cursorKey()
+	if err := m.layoutColumns(); err != nil {
+		m.Error(fmt.Sprintf("Failed to get columns: %v", err))
+		return nil
+	}
+	status := "Nested fields are shown whole"
+	if m.flatten > 0 {
+		status = fmt.Sprintf("Nested fields flattened %v level(s) deep", m.flatten)
+	}
+	if dropped := m.pruneSort(); len(dropped) > 0 {
+		m.Info(fmt.Sprintf("%v, the sort by %v was cleared as the column is gone", status, strings.Join(dropped, ", ")))
+	} else if keys := m.activeSort(); len(keys) > 0 {
+		m.Info(fmt.Sprintf("%v, still sorted by %v", status, describeSort(keys)))
+	} else {
+		m.Info(status)
+	}
+	return tea.Batch(m.ensureSort(), m.reloadRows(current))
+}
+
+// relayout lays the columns out again once a page brought nested fields the table has no column
+// for yet and loads the page again with them.
+func (m *Model) relayout(page pageRequest) tea.Cmd {
+	m.setColumns()
+	if dropped := m.pruneSort(); len(dropped) > 0 {
+		m.Info(fmt.Sprintf("The sort by %v was cleared, other documents hold more than objects there", strings.Join(dropped, ", ")))
+	}
+	sorting := m.ensureSort()
+	if !page.reset {
+		current, _ := m.cursorKey()
+		return tea.Batch(sorting, m.reloadRows(current))
+	}
+	m.clearRows()
+	return tea.Batch(sorting, m.requestPage(page))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatteningMerge(t *testing.T) {
+	fields := [][]string{{"id"}, {"meta"}}
+	flat := flattenFields(fields, []kmap{{"id": "a", "meta": map[string]any{"a": 1.0}}}, 2)
+	if !reflect.DeepEqual(flat, flattening{"id": nil, "meta": {"a"}}) {
+		t.Fatalf("first page: %v", flat)
+	}
+
+	// nested fields of later pages are added in order, pages without news change nothing
+	later := flattenFields(fields, []kmap{{"meta": map[string]any{"b": map[string]any{"x": 1.0}, "a": 2.0}}}, 2)
+	if !flat.merge(later) {
+		t.Fatal("merge of a new nested field reported no change")
+	}
+	if flat.merge(later) {
+		t.Fatal("merging the same page again reported a change")
+	}
+	columns, parents := flattenColumns(fields, flat)
+	want := [][]string{{"id"}, {"meta.a"}, {"meta.b.x"}}
+	if !reflect.DeepEqual(columns, want) || !reflect.DeepEqual(parents, []int{0, 1, 1}) {
+		t.Fatalf("columns %v %v, want %v [0 1 1]", columns, parents, want)
+	}
+
+	// a nested field holding something else is kept whole, and so is a field
+	flat.merge(flattening{"meta": {"a", "b"}})
+	if !reflect.DeepEqual(flat["meta"], []string{"a", "b"}) {
+		t.Fatalf("meta.b kept whole: %v", flat["meta"])
+	}
+	flat.merge(flattening{"meta": nil})
+	if columns, _ := flattenColumns(fields, flat); !reflect.DeepEqual(columns, fields) {
+		t.Fatalf("meta kept whole: %v", columns)
+	}
+}
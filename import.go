@@ -0,0 +1,434 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IMPORT_BATCH is how many documents are written per transaction unless --batch is given.
+const IMPORT_BATCH = 5000
+
+// importOptions describe an import, they are given like command line flags after the file name.
+type importOptions struct {
+	path       string
+	collection string
+	keyField   string
+	mode       string
+	dryRun     bool
+	batch      int
+}
+
+// importDoneMsg is sent once an import finished or failed.
+type importDoneMsg struct {
+	opts   importOptions
+	result store.ImportResult
+	err    error
+}
+
+func parseImportArgs(args []string, collection string) (importOptions, error) {
+	opts := importOptions{collection: collection}
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.collection, "into", collection, "collection to import into, created if it doesn't exist")
+	fs.StringVar(&opts.keyField, "key", KEY_COLUMN, "field holding the document key, documents without it get a generated key")
+	fs.StringVar(&opts.mode, "mode", "insert", "insert skips existing keys, upsert overwrites them, replace swaps the file in for the collection")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "only report what the import would do")
+	fs.IntVar(&opts.batch, "batch", IMPORT_BATCH, "documents written per transaction")
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return opts, err
+	}
+	if len(positional) != 1 {
+		return opts, fmt.Errorf("expected a single file to import, got %v", len(positional))
+	}
+	opts.path = positional[0]
+	switch opts.mode {
+	case "insert", "upsert", "replace":
+	default:
+		return opts, fmt.Errorf("unknown mode %q, expected insert, upsert or replace", opts.mode)
+	}
+	if opts.batch < 1 {
+		return opts, fmt.Errorf("batch must be at least 1, got %v", opts.batch)
+	}
+	if opts.collection == "" {
+		return opts, errors.New("no collection to import into, use --into")
+	}
+	return opts, nil
+}
+
+func (m Model) showImportPrompt() (tea.Model, tea.Cmd) {
+	if m.driver == nil {
+		return m, nil
+	}
+	if m.transfer != nil {
+		m.Error(fmt.Sprintf("Still %v %v", m.transfer.verb, m.transfer.path))
+		return m, nil
+	}
+	m.importInput = textinput.New()
+	m.importInput.Prompt = "import: "
+	m.importInput.Placeholder = "file.ndjson --into collection --key _key --mode insert|upsert|replace --dry-run"
+	m.state = ImportPrompt
+	return m, m.importInput.Focus()
+}
+
+func (m Model) updateImportPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		m.state = Normal
+		m.importInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		collection := ""
+		if len(m.collections) > 0 {
+			collection = m.collections[m.activeCollection]
+		}
+		opts, err := parseImportArgs(strings.Fields(m.importInput.Value()), collection)
+		if err != nil {
+			m.Error(fmt.Sprintf("Import: %v", err))
+			return m, nil
+		}
+		if !opts.dryRun && m.driver.ReadOnly {
+			m.Error(fmt.Sprintf("Can't import, %v", store.ErrReadOnly))
+			return m, nil
+		}
+		m.state = Normal
+		m.importInput.Blur()
+		return m.startImport(opts)
+	}
+	var cmd tea.Cmd
+	m.importInput, cmd = m.importInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) startImport(opts importOptions) (tea.Model, tea.Cmd) {
+	info, err := os.Stat(opts.path)
+	if err != nil {
+		m.Error(fmt.Sprintf("Import failed: %v", err))
+		return m, nil
+	}
+	columns, _ := m.driver.FieldsOf(opts.collection)
+
+	verb := "importing"
+	if opts.dryRun {
+		verb = "checking"
+	}
+	m.transfer = newTransfer(verb, opts.path, info.Size())
+	t, db := m.transfer, m.driver
+	run := func() tea.Msg {
+		defer close(t.progress)
+		result, err := importFile(db, opts, columns, t.report)
+		return importDoneMsg{opts: opts, result: result, err: err}
+	}
+	return m, tea.Batch(run, waitTransferProgress(t.progress))
+}
+
+func (m Model) importDone(msg importDoneMsg) (tea.Model, tea.Cmd) {
+	m.transfer = nil
+	opts, r := msg.opts, msg.result
+	file := filepath.Base(opts.path)
+	if msg.err != nil {
+		switch {
+		case opts.dryRun:
+			m.Error(fmt.Sprintf("Dry run of %v failed: %v", file, msg.err))
+		case opts.mode == "replace":
+			m.Error(fmt.Sprintf("Import of %v failed, %v was left as it was: %v", file, opts.collection, msg.err))
+		default:
+			// batches written before the failure stay, the counts say how far it got
+			m.Error(fmt.Sprintf("Import of %v failed after %v created, %v updated: %v", file, r.Created, r.Updated, msg.err))
+		}
+	} else if opts.dryRun {
+		m.Info(fmt.Sprintf("Dry run of %v into %v: %v would be created, %v updated, %v rejected, %v removed",
+			file, opts.collection, r.Created, r.Updated, r.Rejected, r.Removed))
+		return m, nil
+	} else {
+		m.Success(fmt.Sprintf("Imported %v into %v: %v created, %v updated, %v rejected, %v removed",
+			file, opts.collection, r.Created, r.Updated, r.Rejected, r.Removed))
+	}
+	if opts.dryRun {
+		return m, nil
+	}
+
+	colls, err := m.driver.GetCollections()
+	if err != nil {
+		m.Error(fmt.Sprintf("Failed to get collections: %v", err))
+		return m, nil
+	}
+	m.collections = colls
+	for i, coll := range colls {
+		if coll == opts.collection {
+			m.activeCollection = i
+		}
+	}
+	m.staleSorts()
+	return m, m.loadCollection()
+}
+
+// importFile reads the documents of the file and writes them in batches, report gets the bytes read so far.
+func importFile(db *store.DB, opts importOptions, columns [][]string, report func(read int64)) (store.ImportResult, error) {
+	f, err := os.Open(opts.path)
+	if err != nil {
+		return store.ImportResult{}, err
+	}
+	defer f.Close()
+	counter := &countingReader{r: f, report: report}
+	r := bufio.NewReaderSize(counter, 1<<16)
+
+	importer, err := db.NewImporter(opts.collection, opts.mode != "insert", opts.dryRun)
+	if err != nil {
+		return store.ImportResult{}, err
+	}
+	if opts.mode == "replace" {
+		if err := importer.Replace(); err != nil {
+			return importer.Result, err
+		}
+	}
+
+	// fields in the order they were first seen, the ones the collection didn't record yet are added to it
+	var fields []string
+	seenFields := map[string]bool{}
+	var keys [][]byte
+	var docs []any
+	flush := func() error {
+		if len(docs) == 0 {
+			return nil
+		}
+		err := importer.Write(keys, docs)
+		keys, docs = keys[:0], docs[:0]
+		return err
+	}
+
+	err = readDocuments(r, exportFormat(opts.path), columns, func(value any) error {
+		doc, ok := value.(map[string]any)
+		if !ok {
+			importer.Reject()
+			return nil
+		}
+		var key []byte
+		if v, ok := doc[opts.keyField]; ok && v != nil {
+			key = []byte(keyString(v))
+			if opts.keyField == KEY_COLUMN {
+				// the key column only exists in exports, it isn't part of the document
+				delete(doc, KEY_COLUMN)
+			}
+		}
+		var names []string
+		for name := range doc {
+			if !seenFields[name] {
+				seenFields[name] = true
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		fields = append(fields, names...)
+		keys = append(keys, key)
+		docs = append(docs, doc)
+		if len(docs) >= opts.batch {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err == nil {
+		err = importer.Commit()
+	}
+	if err != nil {
+		return importer.Result, errors.Join(err, importer.Abort())
+	}
+	report(counter.read)
+
+	if !opts.dryRun {
+		if err := db.RegisterCollection(opts.collection, fields); err != nil {
+			return importer.Result, err
+		}
+	}
+	return importer.Result, nil
+}
+
+func keyString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// readDocuments calls fn with every value of a JSON array, a single JSON value, newline delimited JSON or
+// the rows of a CSV file. Numbers are kept as written so large integers survive the import.
+func readDocuments(r *bufio.Reader, format string, columns [][]string, fn func(value any) error) error {
+	if format == "csv" {
+		return readCSV(r, columns, fn)
+	}
+
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if format == "json" {
+		first, err := peekNonSpace(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first == '[' {
+			if _, err := dec.Token(); err != nil {
+				return err
+			}
+			for dec.More() {
+				var value any
+				if err := dec.Decode(&value); err != nil {
+					return err
+				}
+				if err := fn(value); err != nil {
+					return err
+				}
+			}
+			_, err := dec.Token()
+			return err
+		}
+	}
+	for {
+		var value any
+		err := dec.Decode(&value)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+}
+
+// readCSV turns every row into a document, headers that match a field alias of the collection
+// take the name the field is stored under. Empty cells are left out of the document.
+func readCSV(r io.Reader, columns [][]string, fn func(value any) error) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(header))
+	for i, h := range header {
+		names[i] = h
+		for _, aliases := range columns {
+			for _, alias := range aliases {
+				if strings.EqualFold(alias, h) {
+					names[i] = aliases[len(aliases)-1]
+				}
+			}
+		}
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(record) != len(names) {
+			// a row that doesn't line up with the header is passed on as a rejected value
+			if err := fn(nil); err != nil {
+				return err
+			}
+			continue
+		}
+		doc := map[string]any{}
+		for i, cell := range record {
+			if cell == "" {
+				continue
+			}
+			if names[i] == KEY_COLUMN {
+				doc[names[i]] = cell
+				continue
+			}
+			doc[names[i]] = inferValue(cell)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// inferValue reads a CSV cell as a bool, number, null or nested JSON where it looks like one, else as a string.
+func inferValue(cell string) any {
+	switch cell {
+	case "true", "false":
+		return cell == "true"
+	case "null":
+		return nil
+	}
+	if _, err := strconv.ParseFloat(cell, 64); err == nil && json.Valid([]byte(cell)) {
+		return json.Number(cell)
+	}
+	if c := cell[0]; c == '{' || c == '[' {
+		if value, err := parseJSON(cell); err == nil {
+			return value
+		}
+	}
+	return cell
+}
+
+// countingReader keeps track of how much of the file was read.
+type countingReader struct {
+	r      io.Reader
+	read   int64
+	last   int64
+	report func(read int64)
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if c.read-c.last >= 1<<20 {
+		c.last = c.read
+		c.report(c.read)
+	}
+	return n, err
+}
+
+// peekNonSpace returns the first byte that isn't white space without consuming it.
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			if _, err := r.ReadByte(); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		return b[0], nil
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"strings"
+)
+
+// insertSession is the document being written, the key is generated when keyInput is left empty.
+type insertSession struct {
+	collection string
+	keyInput   textinput.Model
+	editingKey bool
+}
+
+// skeleton builds a document with every field of the collection, set to the zero value of
+// the type seen in the loaded rows or null when the field was never seen with a value.
+func (m Model) skeleton() string {
+	if len(m.fields) == 0 {
+		return "{\n  \n}"
+	}
+	var lines []string
+	for i, colAliases := range m.fields {
+		var value any
+		for c, field := range m.columnFields {
+			if field != i {
+				continue
+			}
+			if m.columns[c][0] != colAliases[0] {
+				// flattened into a column per nested field
+				value = map[string]any{}
+				break
+			}
+			for _, row := range m.cleanRowData {
+				if row[c] != nil {
+					value = zeroOf(row[c])
+					break
+				}
+			}
+		}
+		name, _ := json.Marshal(colAliases[len(colAliases)-1])
+		v, _ := json.Marshal(value)
+		lines = append(lines, fmt.Sprintf("  %s: %s", name, v))
+	}
+	return "{\n" + strings.Join(lines, ",\n") + "\n}"
+}
+
+func zeroOf(v any) any {
+	switch v.(type) {
+	case float64:
+		return 0
+	case string:
+		return ""
+	case bool:
+		return false
+	case []any:
+		return []any{}
+	case map[string]any:
+		return map[string]any{}
+	}
+	return nil
+}
+
+// beginInsert opens the editor with a skeleton of the active collection.
+func (m Model) beginInsert() (tea.Model, tea.Cmd) {
+	if m.driver == nil || len(m.collections) == 0 {
+		return m, nil
+	}
+	if m.driver.ReadOnly {
+		m.Error(fmt.Sprintf("Can't insert, %v", store.ErrReadOnly))
+		return m, nil
+	}
+
+	keyInput := textinput.New()
+	keyInput.Prompt = "key: "
+	keyInput.Placeholder = "leave empty to generate one"
+	m.inserting = insertSession{
+		collection: m.collections[m.activeCollection],
+		keyInput:   keyInput,
+	}
+	m.editor = textarea.New()
+	m.editor.CharLimit = 0
+	m.editor.SetWidth(m.window.width - 6)
+	m.editor.SetHeight(m.window.height - 16)
+	m.editor.SetValue(m.skeleton())
+	m.state = InsertDocument
+	return m, m.editor.Focus()
+}
+
+func (m Model) updateInsert(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg.String() {
+	case "esc":
+		m.state = Normal
+		m.editor.Blur()
+		m.inserting = insertSession{}
+		return m, nil
+	case "tab":
+		m.inserting.editingKey = !m.inserting.editingKey
+		if m.inserting.editingKey {
+			m.editor.Blur()
+			return m, m.inserting.keyInput.Focus()
+		}
+		m.inserting.keyInput.Blur()
+		return m, m.editor.Focus()
+	case "ctrl+s":
+		return m.insertDocument()
+	}
+
+	if m.inserting.editingKey {
+		m.inserting.keyInput, cmd = m.inserting.keyInput.Update(msg)
+		return m, cmd
+	}
+	m.editor, cmd = m.editor.Update(msg)
+	return m, cmd
+}
+
+// insertDocument stores the edited document and puts the cursor on its row.
+func (m Model) insertDocument() (tea.Model, tea.Cmd) {
+	value, err := parseJSON(m.editor.Value())
+	if err != nil {
+		m.Error(fmt.Sprintf("Invalid JSON: %v", err))
+		return m, nil
+	}
+	doc, ok := value.(map[string]any)
+	if !ok {
+		m.Error("A document has to be a JSON object")
+		return m, nil
+	}
+
+	collection := m.inserting.collection
+	key := []byte(strings.TrimSpace(m.inserting.keyInput.Value()))
+	ids, err := m.driver.Insert(collection, [][]byte{key}, []any{doc}, false)
+	if err != nil {
+		m.Error(fmt.Sprintf("Insert failed: %v", err))
+		return m, nil
+	}
+	m.state = Normal
+	m.editor.Blur()
+	m.inserting = insertSession{}
+
+	m.staleSorts()
+	cmd := m.loadCollection()
+	m.Success(fmt.Sprintf("Inserted %v into %v", string(ids[0]), collection))
+	jump := m.jumpTo(rowJump{key: ids[0], missing: fmt.Sprintf("Inserted %v, it doesn't match the current filter", string(ids[0]))})
+	return m, tea.Batch(cmd, jump, m.ClearInfoAfter("3s"))
+}
+
+// rowJump is a row the cursor goes to once it is loaded. The document view opens on path when document
+// is set, otherwise the cursor moves to the column showing path. missing is reported when the row never shows up.
+type rowJump struct {
+	key      []byte
+	path     string
+	document bool
+	missing  string
+}
+
+// jumpTo puts the cursor on the row stored under key. When it isn't loaded the table is loaded again
+// around it in the background, the rows shown stay when it isn't in the table at all.
+func (m *Model) jumpTo(jump rowJump) tea.Cmd {
+	m.jump = nil
+	if m.landJump(jump) {
+		return nil
+	}
+	m.jump = &jump
+	return m.requestPage(pageRequest{reset: true, from: jump.key})
+}
+
+// landJump moves the cursor onto the row of the jump if it is loaded.
+func (m *Model) landJump(jump rowJump) bool {
+	for i, k := range m.rowKeys {
+		if !bytes.Equal(k, jump.key) {
+			continue
+		}
+		m.setCursor(i)
+		switch {
+		case jump.document:
+			m.showRecord = true
+			m.syncRecord()
+			m.focusPath(jump.path)
+		case jump.path != "":
+			m.moveToColumn(jump.path)
+		}
+		return true
+	}
+	return false
+}
+
+func (m *Model) jumpMissing(jump rowJump) {
+	if jump.missing != "" {
+		m.Error(jump.missing)
+	}
+}
+
+func (m Model) RenderInsert() string {
+	title := fmt.Sprintf("New document in %v", logoStyle.Copy().PaddingLeft(0).Render(m.inserting.collection))
+	return fmt.Sprintf("%v\n\n%v\n\n%v\n\n[ctrl+s] insert    [tab] switch between key and document    [esc] cancel",
+		title, m.inserting.keyInput.View(), m.editor.View())
+}
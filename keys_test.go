@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"reflect"
+	"testing"
+)
+
+// every key does one thing, a key bound twice only ever reaches the binding checked first
+func TestKeysUnique(t *testing.T) {
+	bound := map[string]string{}
+	v := reflect.ValueOf(keys)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		binding, ok := v.Field(i).Interface().(key.Binding)
+		if !ok {
+			continue
+		}
+		for _, k := range binding.Keys() {
+			if other, ok := bound[k]; ok {
+				t.Errorf("%q is bound to both %v and %v", k, other, name)
+			}
+			bound[k] = name
+		}
+	}
+}
@@ -2,43 +2,79 @@ package main
 
 import (
 	"bingoviewer/entle"
-	"encoding/json"
+	"bingoviewer/flasher"
+	"bingoviewer/picker"
+	"bingoviewer/query"
+	"bingoviewer/store"
 	"errors"
+	"flag"
 	"fmt"
 	stick "github.com/76creates/stickers"
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	zone "github.com/lrstanley/bubblezone"
-	"github.com/muesli/reflow/wordwrap"
-	"github.com/nokusukun/bingo"
-	"github.com/sqweek/dialog"
 	"os"
 	"strings"
 	"time"
-	"unicode"
 )
 
 const RESIZE_TICK = 150
 
+// OPEN_TIMEOUT is how long opening a database waits for the file lock unless --timeout is given.
+const OPEN_TIMEOUT = 5 * time.Second
+
+const (
+	snapshotFlash = "snapshot"
+	deleteFlash   = "delete"
+)
+
 // keyMap defines a set of keybindings. To work for help it must satisfy
 // key.Map. It could also very easily be a map[string]key.Binding.
 type keyMap struct {
-	Up     key.Binding
-	Down   key.Binding
-	Left   key.Binding
-	Right  key.Binding
-	Help   key.Binding
-	Quit   key.Binding
-	F1     key.Binding
-	Escape key.Binding
-	Tab    key.Binding
-	Open   key.Binding
-	Enter  key.Binding
-	PgUp   key.Binding
-	PgDn   key.Binding
+	Up       key.Binding
+	Down     key.Binding
+	Left     key.Binding
+	Right    key.Binding
+	Help     key.Binding
+	Quit     key.Binding
+	F1       key.Binding
+	Escape   key.Binding
+	Tab      key.Binding
+	Open     key.Binding
+	Enter    key.Binding
+	PgUp     key.Binding
+	PgDn     key.Binding
+	Copy     key.Binding
+	Query    key.Binding
+	Sort     key.Binding
+	ThenBy   key.Binding
+	Edit     key.Binding
+	Delete   key.Binding
+	Insert   key.Binding
+	Export   key.Binding
+	Import   key.Binding
+	Select   key.Binding
+	Visual   key.Binding
+	Schema   key.Binding
+	Validate key.Binding
+	CopyPath key.Binding
+	Next     key.Binding
+	Prev     key.Binding
+	First    key.Binding
+	Last     key.Binding
+	Flatten  key.Binding
+	Search   key.Binding
+	Watch    key.Binding
+	Diff     key.Binding
+	Raw      key.Binding
+	Stats    key.Binding
+	KeyCol   key.Binding
 }
 
 // ShortHelp returns keybindings to be shown in the mini help view. It's part
@@ -51,7 +87,9 @@ func (k keyMap) ShortHelp() []key.Binding {
 // key.Map interface.
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Tab, k.Enter, k.PgUp, k.PgDn},
+		{k.Tab, k.Enter, k.PgUp, k.PgDn, k.Copy, k.CopyPath, k.Query, k.Search, k.Sort, k.ThenBy, k.Edit},
+		{k.Insert, k.Select, k.Visual, k.Delete, k.Export, k.Import, k.Schema, k.Validate, k.Flatten, k.Watch, k.Diff, k.Raw, k.Stats, k.KeyCol},
+		{k.Next, k.Prev, k.First, k.Last},
 		{k.Up, k.Down, k.Left, k.Right}, // first column
 		{k.Open, k.Help, k.Quit},        // second column
 	}
@@ -110,6 +148,106 @@ var keys = keyMap{
 		key.WithKeys("pgdown"),
 		key.WithHelp("pg down", "go down one page"),
 	),
+	Copy: key.NewBinding(
+		key.WithKeys("y"),
+		key.WithHelp("y", "copy record"),
+	),
+	Query: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "filter rows"),
+	),
+	Sort: key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "sort by column"),
+	),
+	ThenBy: key.NewBinding(
+		key.WithKeys("S"),
+		key.WithHelp("S", "then sort by column"),
+	),
+	Edit: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "edit field"),
+	),
+	Insert: key.NewBinding(
+		key.WithKeys("i"),
+		key.WithHelp("i", "insert document"),
+	),
+	Export: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "export view"),
+	),
+	Import: key.NewBinding(
+		key.WithKeys("I"),
+		key.WithHelp("I", "import file"),
+	),
+	Delete: key.NewBinding(
+		key.WithKeys("d"),
+		key.WithHelp("d", "delete row(s)"),
+	),
+	Select: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "select row"),
+	),
+	Visual: key.NewBinding(
+		key.WithKeys("v"),
+		key.WithHelp("v", "select range"),
+	),
+	Schema: key.NewBinding(
+		key.WithKeys("P"),
+		key.WithHelp("P", "schema report"),
+	),
+	Validate: key.NewBinding(
+		key.WithKeys("V"),
+		key.WithHelp("V", "validate"),
+	),
+	CopyPath: key.NewBinding(
+		key.WithKeys("Y"),
+		key.WithHelp("Y", "copy path"),
+	),
+	Next: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "next document"),
+	),
+	Prev: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "previous document"),
+	),
+	First: key.NewBinding(
+		key.WithKeys("g"),
+		key.WithHelp("g", "first document"),
+	),
+	Last: key.NewBinding(
+		key.WithKeys("G"),
+		key.WithHelp("G", "last document"),
+	),
+	Flatten: key.NewBinding(
+		key.WithKeys("F"),
+		key.WithHelp("F", "flatten nested fields"),
+	),
+	Search: key.NewBinding(
+		key.WithKeys("f"),
+		key.WithHelp("f", "search every collection"),
+	),
+	Watch: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "watch the file for changes"),
+	),
+	Diff: key.NewBinding(
+		key.WithKeys("D"),
+		key.WithHelp("D", "compare with another database"),
+	),
+	Raw: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "browse the raw buckets"),
+	),
+	Stats: key.NewBinding(
+		key.WithKeys("T"),
+		key.WithHelp("T", "database statistics"),
+	),
+	KeyCol: key.NewBinding(
+		key.WithKeys("K"),
+		key.WithHelp("K", "show the key column"),
+	),
 }
 
 type screen struct {
@@ -122,6 +260,22 @@ type State int
 const (
 	Normal State = iota
 	ViewDocument
+	FilePicker
+	Opening
+	QueryBar
+	EditField
+	InsertDocument
+	ExportPrompt
+	ImportPrompt
+	SchemaPanel
+	ValidatePrompt
+	ValidationPanel
+	SearchPrompt
+	SearchPanel
+	DiffPrompt
+	DiffPanel
+	RawBrowser
+	StatsPanel
 )
 
 type Message struct {
@@ -141,7 +295,8 @@ func (m Message) FullRender() string {
 
 type Model struct {
 	DatabaseFile     string
-	driver           *bingo.Driver
+	driver           *store.DB
+	readOnly         bool
 	help             help.Model
 	keys             keyMap
 	window           screen
@@ -152,21 +307,154 @@ type Model struct {
 	activeCollection int
 	collections      []string
 	columns          [][]string
+	rowKeys          [][]byte
 	rowData          [][]any
 	cleanRowData     [][]any
 	table            *stick.Table
 
+	// fields are the fields bingo recorded for the active collection, columns are split from them
+	// into parent.child columns flatten levels deep and columnFields is the field of each column
+	fields       [][]string
+	columnFields []int
+	flatten      int
+	// flat is how the fields are flattened, merged from every page loaded since the layout of
+	// flatOf, the collection and depth it was found for
+	flat   flattening
+	flatOf string
+	// showKey adds the hidden KEY_COLUMN in front of the columns, its columnFields entry is -1
+	showKey bool
+
+	// loadSeq changes every time the collection is reloaded so pages of the previous one are dropped.
+	// The loaded rows are a window of the collection starting at row rowOffset, -1 when that isn't
+	// known, loadedFirst and loadedAll are set when the window reaches the top or the bottom of it.
+	loadSeq     int
+	rowOffset   int
+	loadedFirst bool
+	loadedAll   bool
+	loadingRows bool
+	// rowCount is the number of documents counted in the background, countSeq drops counts of an
+	// earlier reload since pages are reloaded without counting again
+	rowCount int
+	countSeq int
+	// reloadColumn is the column the cursor goes back to once a reload arrives, announce
+	// reports how many rows the first page of a collection brought
+	reloadColumn int
+	announce     bool
+	// jump is the row the cursor goes to once the pages loading in the background reach it
+	jump *rowJump
+
 	showRecord bool
 	viewport   viewport.Model
+	// record is the document shown in the record view, read by its key
+	record    kmap
+	recordKey []byte
+	recordErr error
+
+	// startup holds the command line selection until the first database is opened
+	startup Options
+	picker  picker.Model
+
+	flash       flasher.Model
+	deleteFlash flasher.Model
+	// deleting holds the keys waiting on the answer to the delete question
+	deleting [][]byte
+	// lockedFile is the database waiting on the answer to the snapshot question
+	lockedFile string
+
+	openTimeout time.Duration
+	openSeq     int
+	opening     opening
+	spinner     spinner.Model
+
+	// treeCursor is the selected node of the document view and treeExpanded holds the nodes opened
+	// or closed by hand, by path so they stay that way from one document to the next
+	treeCursor   int
+	treeExpanded map[string]bool
+	editor       textarea.Model
+	editing      editSession
+	inserting    insertSession
+
+	// transfer is the export or import running in the background, the inputs ask for their file
+	transfer    *transfer
+	exportInput textinput.Model
+	importInput textinput.Model
+
+	// selected holds the keys of the rows picked for a bulk delete, visualAnchor is the
+	// row a range selection started on and visualBase what was selected before it
+	selected     map[string]bool
+	visualAnchor int
+	visualBase   map[string]bool
+
+	// sorts holds the sort keys of each collection, sorted the order their rows were sorted in and
+	// sortScan the sort running in the background
+	sorts    map[string][]sortKey
+	sorted   map[string]*sortedRows
+	sortScan *sortScan
+	sortSeq  int
+
+	// queries holds the active filter of each collection, queryHistory the past ones
+	queries      map[string]*query.Query
+	queryHistory map[string][]string
+	queryInput   textinput.Model
+	historyPos   int
+
+	schemaView schemaPanel
+
+	// validations holds the schema attached to each collection and the result of its last pass,
+	// validationCursor is the selected violation of the validation panel
+	validations      map[string]*validation
+	validationCursor int
+	schemaInput      textinput.Model
+
+	// search is the search across every collection, running or done, searchCursor the selected hit
+	search       *search
+	searchInput  textinput.Model
+	searchCursor int
+
+	// watch reloads the active collection when the database file changes, nil while watch mode is off,
+	// watchSeq tells the ticks of a watch apart from those of one that was turned off
+	watch    *watch
+	watchSeq int
+
+	// diffView compares the open database with another one, diffInput asks for its path
+	diffView  diffPanel
+	diffInput textinput.Model
+
+	// raw is the browser of the bbolt buckets under the collections
+	raw rawBrowser
+
+	statsView statsPanel
 }
 
-func NewModel() Model {
-	return Model{
-		help:   help.New(),
-		keys:   keys,
-		window: screen{},
-		table:  stick.NewTable(0, 0, []string{}),
+func NewModel(opts Options) Model {
+	m := Model{
+		help:        help.New(),
+		keys:        keys,
+		window:      screen{},
+		table:       stick.NewTable(0, 0, []string{}),
+		startup:     opts,
+		readOnly:    opts.ReadOnly,
+		flash:       flasher.New(snapshotFlash),
+		deleteFlash: flasher.New(deleteFlash, flasher.Error),
+
+		openTimeout: opts.Timeout,
+		flatten:     opts.Flatten,
+
+		sorts:        map[string][]sortKey{},
+		sorted:       map[string]*sortedRows{},
+		selected:     map[string]bool{},
+		visualAnchor: -1,
+		queries:      map[string]*query.Query{},
+		queryHistory: map[string][]string{},
+		queryInput:   newQueryInput(),
+		validations:  map[string]*validation{},
+		treeExpanded: map[string]bool{},
+	}
+	if opts.Watch {
+		m.watchSeq = 1
+		m.watch = &watch{seq: m.watchSeq}
 	}
+	return m
 }
 
 func resizeTick() tea.Cmd {
@@ -176,9 +464,22 @@ func resizeTick() tea.Cmd {
 }
 
 func (m Model) Init() tea.Cmd {
-	return resizeTick()
+	var watchCmd tea.Cmd
+	if m.watch != nil {
+		watchCmd = tickWatch(m.watch.seq)
+	}
+	if m.startup.DatabaseFile != "" {
+		file := m.startup.DatabaseFile
+		return tea.Batch(resizeTick(), watchCmd, func() tea.Msg {
+			return OpenFile(file)
+		})
+	}
+	return tea.Batch(resizeTick(), watchCmd)
 }
 
+// OpenFile asks the model to open the database at the given path.
+type OpenFile string
+
 type Event int
 
 const (
@@ -227,10 +528,81 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case OpenFile:
+		return m.openDatabase(string(msg))
+	case rowsLoadedMsg:
+		return m.rowsLoaded(msg)
+	case sortedMsg:
+		return m.sortDone(msg)
+	case rowCountMsg:
+		if msg.seq == m.countSeq && msg.err == nil {
+			m.rowCount = msg.count
+		}
+		return m, nil
+	case databaseOpenedMsg:
+		return m.databaseOpened(msg)
+	case databaseOpenFailedMsg:
+		return m.databaseOpenFailed(msg)
+	case spinner.TickMsg:
+		if m.state != Opening {
+			return m, nil
+		}
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	case picker.SelectMsg:
+		m.state = Normal
+		return m.openDatabase(msg.Path)
+	case picker.CancelMsg:
+		m.state = Normal
+		m.Error("Open database cancelled")
+		return m, nil
+	case transferProgressMsg:
+		return m.transferProgress(msg)
+	case exportDoneMsg:
+		return m.exportDone(msg)
+	case importDoneMsg:
+		return m.importDone(msg)
+	case schemaScannedMsg:
+		return m.schemaScanned(msg)
+	case validatedMsg:
+		return m.validated(msg)
+	case searchResultsMsg:
+		return m.searchResults(msg)
+	case statsDoneMsg:
+		return m.statsDone(msg)
+	case diffDoneMsg:
+		return m.diffDone(msg)
+	case watchTickMsg:
+		return m.watchTick(msg)
+	case watchReloadedMsg:
+		return m.watchReloaded(msg)
+	case flasher.FlashEvent:
+		var deleteCmd tea.Cmd
+		m.flash, cmd = m.flash.Update(msg)
+		m.deleteFlash, deleteCmd = m.deleteFlash.Update(msg)
+		return m, tea.Batch(cmd, deleteCmd)
+	case flasher.FlashAnswerMsg:
+		if msg.Id == snapshotFlash {
+			file := m.lockedFile
+			m.lockedFile = ""
+			if !msg.Yes {
+				m.Error("Open database cancelled")
+				return m, nil
+			}
+			return m.openSnapshot(file)
+		}
+		if msg.Id == deleteFlash {
+			if !msg.Yes {
+				m.deleting = nil
+				m.Info("Delete cancelled")
+				return m, m.ClearInfoAfter("3s")
+			}
+			return m.deleteRows()
+		}
 	case Event:
 		switch msg {
 		case OpenDialog:
-			return m.loadDatabaseDialog()
+			return m.showFilePicker()
 		case ClearMsg:
 			m.lastMsg = len(m.messages)
 		}
@@ -239,9 +611,19 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.help.Width = msg.Width
 			m.window.width = entle.Width()
 			m.window.height = entle.Height()
+			m.picker.Width, m.picker.Height = m.pickerSize()
 			cmd = tea.Batch(cmd, resizeTick())
 		}
 	case tea.MouseMsg:
+		if m.showRecord && m.state == Normal && (msg.Type == tea.MouseWheelUp || msg.Type == tea.MouseWheelDown) {
+			if msg.Type == tea.MouseWheelUp {
+				m.stepRecord(-1)
+			} else {
+				m.stepRecord(1)
+			}
+			cmd = m.loadMoreIfNeeded()
+			break
+		}
 		if msg.Type != tea.MouseLeft {
 			break
 		}
@@ -249,22 +631,167 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		for i := 0; i < len(m.collections); i++ {
 			if zone.Get(m.collections[i]).InBounds(msg) {
 				m.activeCollection = i
-				err := m.getData()
-				if err != nil {
-					m.Error(fmt.Sprintf("Failed to get columns: %v", err))
-				}
+				cmd = m.loadCollection()
 				break
 			}
 		}
+		// terminals keep shift-clicks for text selection, so ctrl or alt add a secondary sort key
+		if column, ok := m.headerAt(msg); ok {
+			cmd = m.toggleSort(column, msg.Ctrl || msg.Alt)
+		}
 	case tea.KeyMsg:
+		if m.flash.Active {
+			m.flash, cmd = m.flash.Update(msg)
+			return m, cmd
+		}
+		if m.deleteFlash.Active {
+			m.deleteFlash, cmd = m.deleteFlash.Update(msg)
+			return m, cmd
+		}
+		if m.state == Opening {
+			switch {
+			case key.Matches(msg, m.keys.Escape):
+				return m.cancelOpening()
+			case key.Matches(msg, m.keys.Quit):
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		if m.state == QueryBar {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateQueryBar(msg)
+		}
+		if m.state == EditField {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateEditor(msg)
+		}
+		if m.state == InsertDocument {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateInsert(msg)
+		}
+		if m.state == ExportPrompt {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateExportPrompt(msg)
+		}
+		if m.state == ImportPrompt {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateImportPrompt(msg)
+		}
+		if m.state == SchemaPanel {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateSchemaPanel(msg)
+		}
+		if m.state == ValidatePrompt {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateValidatePrompt(msg)
+		}
+		if m.state == ValidationPanel {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateValidationPanel(msg)
+		}
+		if m.state == SearchPrompt {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateSearchPrompt(msg)
+		}
+		if m.state == SearchPanel {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateSearchPanel(msg)
+		}
+		if m.state == DiffPrompt {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateDiffPrompt(msg)
+		}
+		if m.state == DiffPanel {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateDiffPanel(msg)
+		}
+		if m.state == RawBrowser {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateRaw(msg)
+		}
+		if m.state == StatsPanel {
+			if msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			return m.updateStatsPanel(msg)
+		}
+		if m.state == FilePicker {
+			if key.Matches(msg, m.keys.Quit) && msg.String() == "ctrl+c" {
+				return m, tea.Quit
+			}
+			m.picker, cmd = m.picker.Update(msg)
+			return m, cmd
+		}
+		if m.showRecord && m.state == Normal {
+			switch msg.String() {
+			case "j", "k", "pgup", "pgdown":
+				// the tree cursor is left where it is, the next move scrolls back to it
+				m.viewport, cmd = m.viewport.Update(msg)
+				return m, cmd
+			}
+		}
 		switch {
+		case m.showRecord && key.Matches(msg, m.keys.Next, m.keys.Prev):
+			if key.Matches(msg, m.keys.Next) {
+				m.stepRecord(1)
+			} else {
+				m.stepRecord(-1)
+			}
+		case m.showRecord && key.Matches(msg, m.keys.First):
+			cmd = m.showFirst()
+			m.stepRecord(0)
+		case m.showRecord && key.Matches(msg, m.keys.Last):
+			cmd = m.showLast()
+			m.stepRecord(0)
 		case key.Matches(msg, m.keys.Up):
+			if m.showRecord {
+				m.moveTreeCursor(-1)
+				break
+			}
 			m.table.CursorUp()
 		case key.Matches(msg, m.keys.Down):
+			if m.showRecord {
+				m.moveTreeCursor(1)
+				break
+			}
 			m.table.CursorDown()
 		case key.Matches(msg, m.keys.Left):
+			if m.showRecord {
+				m.collapseNode()
+				break
+			}
 			m.table.CursorLeft()
 		case key.Matches(msg, m.keys.Right):
+			if m.showRecord {
+				m.expandNode()
+				break
+			}
 			m.table.CursorRight()
 		case key.Matches(msg, m.keys.PgUp):
 			for i := 0; i < m.window.height-8; i++ {
@@ -284,6 +811,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keys.F1):
 			m.showAllMessages = !m.showAllMessages
 		case key.Matches(msg, m.keys.Escape):
+			if !m.showRecord && m.clearSelection() {
+				break
+			}
 			m.showRecord = false
 			return m, m.ClearInfoAfter("10ms")
 		case key.Matches(msg, m.keys.Tab):
@@ -295,17 +825,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.activeCollection < 0 {
 					m.activeCollection = len(m.collections) - 1
 				}
-				err := m.getData()
-				if err != nil {
-					m.Error(fmt.Sprintf("Failed to get columns: %v", err))
-				}
+				cmd = m.loadCollection()
 				break
 			}
 			m.activeCollection = (m.activeCollection + 1) % len(m.collections)
-			err := m.getData()
-			if err != nil {
-				m.Error(fmt.Sprintf("Failed to get columns: %v", err))
-			}
+			cmd = m.loadCollection()
 		case key.Matches(msg, m.keys.Enter):
 			if m.DatabaseFile == "" {
 				break
@@ -314,67 +838,91 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				break
 			}
 			m.showRecord = !m.showRecord
+			// the tree cursor starts on the column the table cursor is on, after the key
+			m.syncRecord()
+			x, _ := m.table.GetCursorLocation()
+			m.focusColumn(x)
+		case key.Matches(msg, m.keys.Copy):
+			if m.DatabaseFile == "" || len(m.rowData) == 0 {
+				break
+			}
+			if m.showRecord {
+				m.copyNodeValue()
+			} else {
+				m.copyRecord()
+			}
+			cmd = tea.Batch(cmd, m.ClearInfoAfter("3s"))
+		case key.Matches(msg, m.keys.CopyPath):
+			if m.showRecord {
+				m.copyNodePath()
+				cmd = tea.Batch(cmd, m.ClearInfoAfter("3s"))
+			}
+		case key.Matches(msg, m.keys.Query):
+			return m.showQueryBar()
+		case key.Matches(msg, m.keys.Edit):
+			return m.beginEdit()
+		case key.Matches(msg, m.keys.Delete):
+			return m.askDelete()
+		case key.Matches(msg, m.keys.Insert):
+			return m.beginInsert()
+		case key.Matches(msg, m.keys.Export):
+			return m.showExportPrompt()
+		case key.Matches(msg, m.keys.Import):
+			return m.showImportPrompt()
+		case key.Matches(msg, m.keys.Schema):
+			return m.showSchema()
+		case key.Matches(msg, m.keys.Validate):
+			return m.showValidation()
+		case key.Matches(msg, m.keys.Search):
+			return m.showSearchPrompt()
+		case key.Matches(msg, m.keys.Diff):
+			return m.showDiffPrompt()
+		case key.Matches(msg, m.keys.Raw):
+			return m.showRaw()
+		case key.Matches(msg, m.keys.Stats):
+			return m.showStats()
+		case key.Matches(msg, m.keys.Watch):
+			cmd = tea.Batch(cmd, m.toggleWatch())
+		case key.Matches(msg, m.keys.Flatten):
+			if !m.showRecord {
+				cmd = tea.Batch(cmd, m.toggleFlatten(), m.ClearInfoAfter("3s"))
+			}
+		case key.Matches(msg, m.keys.KeyCol):
+			if !m.showRecord {
+				cmd = tea.Batch(cmd, m.toggleKeyColumn(), m.ClearInfoAfter("3s"))
+			}
+		case key.Matches(msg, m.keys.Select):
+			if m.showRecord {
+				m.toggleNode()
+				break
+			}
+			m.toggleSelected()
+		case key.Matches(msg, m.keys.Visual):
+			if !m.showRecord {
+				m.toggleVisual()
+			}
+		case key.Matches(msg, m.keys.Sort, m.keys.ThenBy):
+			if !m.showRecord {
+				x, _ := m.table.GetCursorLocation()
+				cmd = m.toggleSort(x, key.Matches(msg, m.keys.ThenBy))
+			}
 		case key.Matches(msg, m.keys.Quit):
 			//m.quitting = true
 			return m, tea.Quit
 		}
+		m.selectVisual()
+		cmd = tea.Batch(cmd, m.loadMoreIfNeeded())
 	}
 
+	m.syncRecord()
+	if m.showRecord && m.state == Normal {
+		m.layoutDocument(true)
+	}
 	return m, cmd
 }
 
-func (m Model) loadDatabaseDialog() (tea.Model, tea.Cmd) {
-	load, err := dialog.File().Filter("Bingo Database", "*.*").Title("Open Database").Load()
-	if err != nil {
-		if errors.Is(err, dialog.ErrCancelled) {
-			m.Error("Open database cancelled")
-		} else {
-			m.Error(fmt.Sprintf("Open database failed: %v", err))
-		}
-		return m, nil
-	}
-	m.DatabaseFile = load
-
-	driverChan := make(chan *bingo.Driver)
-	go func() {
-		driver, err := bingo.NewDriver(bingo.DriverConfiguration{
-			Filename: load,
-		})
-		if err != nil {
-			m.Error(fmt.Sprintf("Open database failed: %v", err))
-			driverChan <- nil
-			return
-		}
-		driverChan <- driver
-	}()
-
-	select {
-	case <-time.After(5 * time.Second):
-		m.Error(fmt.Sprintf("Open database timed out, maybe it's opened sommewhere else?"))
-		return m, nil
-	case driver := <-driverChan:
-		if driver == nil {
-			return m, nil
-		}
-		m.driver = driver
-	}
-
-	if err != nil {
-		m.Error(fmt.Sprintf("Open database failed: %v", err))
-		return m, nil
-	}
-	colls, err := m.driver.GetCollections()
-	if err != nil {
-		m.Error(fmt.Sprintf("Failed to get collections: %v", err))
-		return m, nil
-	}
-	m.collections = colls
-	err = m.getData()
-	if err != nil {
-		m.Error(fmt.Sprintf("Failed to get columns: %v", err))
-	}
-	m.Success(fmt.Sprintf("Opened database: %v", load))
-	return m, m.ClearInfoAfter("3s")
+func (m Model) pickerSize() (int, int) {
+	return m.window.width - 4, m.window.height - 7
 }
 
 func (m Model) dim() (int, int) {
@@ -398,100 +946,15 @@ func (m Model) RenderTabs() string {
 	return tabs.String()
 }
 
+// kmap is a document decoded without its application type. Its Key is nil since the viewer never
+// writes documents through a bingo collection: store writes them to bbolt with explicit keys, so
+// bingo's validator and the hooks an application registers don't run for edits, inserts and imports.
 type kmap map[string]any
 
 func (kmap) Key() []byte {
 	return nil
 }
 
-func (m *Model) lookup(row int) kmap {
-	rowDoc := m.rowData[row]
-	collection := bingo.CollectionFrom[kmap](m.driver, m.collections[m.activeCollection])
-	res := collection.Query(bingo.Query[kmap]{
-		Filter: func(doc kmap) bool {
-			for i, col := range m.columns {
-				for _, colname := range col {
-					if val, ok := doc[colname]; ok {
-						if fmt.Sprintf("%v", val) != rowDoc[i] {
-							return false
-						}
-					}
-				}
-			}
-			return true
-		},
-	})
-	return *res.First()
-}
-
-func (m *Model) getData() error {
-	cols, err := m.driver.FieldsOf(m.collections[m.activeCollection])
-	if err != nil {
-		return err
-	}
-
-	m.columns = cols
-	loadErr := ""
-	var orderedRows [][]any
-	var cleanOrderedRows [][]any
-	collection := bingo.CollectionFrom[kmap](m.driver, m.collections[m.activeCollection])
-	collection.Query(bingo.Query[kmap]{
-		Filter: func(doc kmap) bool {
-			return true
-		},
-	}).Iter(func(docPtr *kmap) error {
-		doc := *docPtr
-		var row []any
-		var cleanRow []any
-		for _, colnames := range m.columns {
-			added := false
-			for _, colname := range colnames {
-				if val, ok := doc[colname]; ok {
-					v := strings.Map(func(r rune) rune {
-						if unicode.IsPrint(r) {
-							return r
-						}
-						return -1
-					}, fmt.Sprintf("%v", val))
-					row = append(row, v)
-					cleanRow = append(cleanRow, val)
-					added = true
-					break
-				}
-			}
-			if !added {
-				row = append(row, "(None)")
-				cleanRow = append(cleanRow, nil)
-			}
-		}
-		if len(row) != len(m.columns) {
-			loadErr = fmt.Sprintf("Row has %v columns, expected %v", len(row), len(m.columns))
-			return nil
-		}
-		orderedRows = append(orderedRows, row)
-		cleanOrderedRows = append(cleanOrderedRows, cleanRow)
-		return nil
-	})
-	if loadErr != "" {
-		m.Error(loadErr)
-	}
-	m.Info(fmt.Sprintf("Loaded %v row(s)", len(orderedRows)))
-	m.rowData = orderedRows
-	m.cleanRowData = cleanOrderedRows
-
-	m.table = stick.NewTable(0, 0, m.Headers())
-	m.table.SetStyles(map[stick.TableStyleKey]lipgloss.Style{
-		stick.TableHeaderStyleKey: accentStyle,
-		stick.TableFooterStyleKey: lipgloss.NewStyle(),
-	})
-	m.table, err = m.table.AddRows(m.rowData)
-	if err != nil {
-		m.Error(fmt.Sprintf("Failed to render table: %v", err))
-	}
-
-	return nil
-}
-
 func (m Model) Headers() []string {
 	var h []string
 	for _, col := range m.columns {
@@ -500,54 +963,36 @@ func (m Model) Headers() []string {
 	return h
 }
 
-func (m *Model) RenderDocumentView() string {
-	if len(m.rowData) == 0 {
-		return "No row data"
+// tableHeaders are the headers with the sort direction of the sorted columns.
+func (m Model) tableHeaders() []string {
+	var h []string
+	for _, col := range m.Headers() {
+		h = append(h, col+m.sortIndicator(col))
 	}
+	return h
+}
 
-	m.viewport.Width = m.window.width - 2
-	m.viewport.Height = m.window.height - 8
-	_, y := m.table.GetCursorLocation()
-	doc := m.cleanRowData[y]
-	var content = strings.Builder{}
-	// get the widest column text width
-	maxWidth := 0
-	for _, colAlias := range m.columns {
-		for _, colname := range colAlias {
-			if len(colname) > maxWidth {
-				maxWidth = len(colname)
-			}
-		}
+// headerAt returns the column whose header was clicked, the columns share the table width evenly.
+func (m Model) headerAt(msg tea.MouseMsg) (int, bool) {
+	z := zone.Get("table")
+	if m.state != Normal || m.showRecord || len(m.rowData) == 0 || !z.InBounds(msg) || msg.Y != z.StartY {
+		return 0, false
 	}
-
-	for i, colAliases := range m.columns {
-		//hasWritten := false
-		//for _, colname := range colAliases {
-		colname := colAliases[len(colAliases)-1]
-		v := doc[i]
-		r, err := json.MarshalIndent(v, "", "  ")
-		if err != nil {
-			return errorStyle.Render(err.Error())
-		}
-		key := logoStyle.Render(colname)
-		val := strings.Map(func(r rune) rune {
-			if unicode.IsPrint(r) || r == '\n' {
-				return r
-			}
-			return -1
-		}, string(r))
-		if val == "null" {
-			val = lipgloss.NewStyle().Foreground(lipgloss.Color("#474747")).Render(val)
-		} else {
-			coloredReturn := lipgloss.NewStyle().Foreground(lipgloss.Color("#e07a00")).Render("↵")
-			val = strings.ReplaceAll(val, "\\n", coloredReturn+"\n")
-		}
-		content.WriteString(fmt.Sprintf("%v%v : %v\n", key, strings.Repeat(" ", maxWidth-len(colname)), val))
+	width := z.EndX - z.StartX + 1
+	if width <= 0 || len(m.columns) == 0 {
+		return 0, false
 	}
+	return (msg.X - z.StartX) * len(m.columns) / width, true
+}
 
-	top := fmt.Sprintf("Table: %v [%v/%v]", m.collections[m.activeCollection], y+1, len(m.rowData))
-	c := wordwrap.String(content.String(), m.viewport.Width-4)
-	m.viewport.SetContent(fmt.Sprintf("%v\n\n%v", top, c))
+func (m *Model) RenderDocumentView() string {
+	if len(m.rowData) == 0 {
+		return "No row data"
+	}
+	if m.recordErr != nil {
+		return errorStyle.Render(m.recordErr.Error())
+	}
+	m.layoutDocument(false)
 	return m.viewport.View()
 }
 
@@ -558,7 +1003,7 @@ func (m *Model) RenderTable() string {
 		return lipgloss.Place(m.window.width-2, m.window.height-10, lipgloss.Center, lipgloss.Center, "No data")
 	}
 
-	return m.table.Render()
+	return zone.Mark("table", m.table.Render())
 }
 
 var (
@@ -572,6 +1017,12 @@ var (
 )
 
 func (m Model) View() string {
+	if m.deleteFlash.Active {
+		return m.deleteFlash.View()
+	}
+	if m.flash.Active {
+		return m.flash.View()
+	}
 
 	// Top Bar
 	top := stick.NewFlexBox(m.window.width-2, 1)
@@ -590,8 +1041,13 @@ func (m Model) View() string {
 	)
 	top.ForceRecalculate()
 	databaseName := m.DatabaseFile
-	if databaseName == "" {
+	switch {
+	case databaseName == "":
 		databaseName = "No Database Opened"
+	case m.driver.IsSnapshot():
+		databaseName += " [snapshot]"
+	case m.driver.ReadOnly:
+		databaseName += " [read-only]"
 	}
 	titleBar = titleBar.SetContent(lipgloss.PlaceHorizontal(titleBar.GetWidth(), lipgloss.Center, databaseName+"     "))
 
@@ -600,6 +1056,10 @@ func (m Model) View() string {
 	content := lipgloss.Place(center.GetWidth(), center.GetHeight(), lipgloss.Center, lipgloss.Center, "Start by opening a database with [o]")
 
 	switch {
+	case m.state == Opening:
+		content = lipgloss.Place(center.GetWidth(), center.GetHeight(), lipgloss.Center, lipgloss.Center, m.RenderOpening())
+	case m.state == FilePicker:
+		content = tableBorderStyle.Width(m.window.width - 2).Height(m.window.height - 7).Render(m.picker.View())
 	case m.showAllMessages:
 		var messages []string
 		// reverse iterate through messages
@@ -610,6 +1070,46 @@ func (m Model) View() string {
 		content = lipgloss.JoinVertical(lipgloss.Top, messages...)
 	case m.DatabaseFile != "":
 		switch {
+		case m.state == EditField:
+			content = lipgloss.JoinVertical(lipgloss.Top,
+				m.RenderTabs(),
+				tableBorderStyle.Width(m.window.width-2).Render(m.RenderEditor()),
+			)
+		case m.state == InsertDocument:
+			content = lipgloss.JoinVertical(lipgloss.Top,
+				m.RenderTabs(),
+				tableBorderStyle.Width(m.window.width-2).Render(m.RenderInsert()),
+			)
+		case m.state == SchemaPanel:
+			content = lipgloss.JoinVertical(lipgloss.Top,
+				m.RenderTabs(),
+				tableBorderStyle.Width(m.window.width-2).Render(m.RenderSchemaPanel()),
+			)
+		case m.state == ValidationPanel:
+			content = lipgloss.JoinVertical(lipgloss.Top,
+				m.RenderTabs(),
+				tableBorderStyle.Width(m.window.width-2).Render(m.RenderValidationPanel()),
+			)
+		case m.state == SearchPanel:
+			content = lipgloss.JoinVertical(lipgloss.Top,
+				m.RenderTabs(),
+				tableBorderStyle.Width(m.window.width-2).Render(m.RenderSearchPanel()),
+			)
+		case m.state == DiffPanel:
+			content = lipgloss.JoinVertical(lipgloss.Top,
+				m.RenderTabs(),
+				tableBorderStyle.Width(m.window.width-2).Render(m.RenderDiffPanel()),
+			)
+		case m.state == RawBrowser:
+			content = lipgloss.JoinVertical(lipgloss.Top,
+				m.RenderTabs(),
+				tableBorderStyle.Width(m.window.width-2).Render(m.RenderRaw()),
+			)
+		case m.state == StatsPanel:
+			content = lipgloss.JoinVertical(lipgloss.Top,
+				m.RenderTabs(),
+				tableBorderStyle.Width(m.window.width-2).Render(m.RenderStatsPanel()),
+			)
 		case m.showRecord:
 			content = lipgloss.JoinVertical(lipgloss.Top,
 				m.RenderTabs(),
@@ -638,7 +1138,10 @@ func (m Model) View() string {
 	bottom := stick.NewFlexBox(m.window.width, 1).SetStyle(accentStyle)
 	leftMsg := fmt.Sprintf("[%v:%v]", m.window.width, m.window.height)
 	if m.DatabaseFile != "" {
-		leftMsg = fmt.Sprintf("[%v:%v] %v row(s)", m.window.width, m.window.height, len(m.rowData))
+		leftMsg = fmt.Sprintf("[%v:%v] %v", m.window.width, m.window.height, m.RenderRowCount())
+	}
+	if m.transfer != nil {
+		leftMsg = fmt.Sprintf("[%v:%v] %v", m.window.width, m.window.height, m.RenderTransfer())
 	}
 	left := accentStyle.Render(leftMsg)
 	right := stick.NewFlexBoxCell(1, 1)
@@ -660,12 +1163,44 @@ func (m Model) View() string {
 	}
 	right.SetContent(accentStyle.Render(lipgloss.PlaceHorizontal(right.GetWidth()-5, lipgloss.Right, msg)))
 
-	return zone.Scan(lipgloss.JoinVertical(lipgloss.Top, titleBorderStyle.Render(top.Render()), center.Render(), bottom.Render(), m.help.View(m.keys)))
+	footer := m.help.View(m.keys)
+	switch m.state {
+	case QueryBar:
+		footer = m.queryInput.View()
+	case ExportPrompt:
+		footer = m.exportInput.View() + "  (.json, .ndjson or .csv)"
+	case ImportPrompt:
+		footer = m.importInput.View()
+	case ValidatePrompt:
+		footer = m.schemaInput.View()
+	case SearchPrompt:
+		footer = m.searchInput.View()
+	case DiffPrompt:
+		footer = m.diffInput.View()
+	case SchemaPanel:
+		if m.schemaView.exporting {
+			footer = m.schemaView.exportInput.View()
+		}
+	}
+	return zone.Scan(lipgloss.JoinVertical(lipgloss.Top, titleBorderStyle.Render(top.Render()), center.Render(), bottom.Render(), footer))
 }
 
 func main() {
+	if name, args, ok := commandArgs(os.Args[1:]); ok {
+		os.Exit(runCommand(name, args))
+	}
+
+	opts, err := parseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		os.Exit(2)
+	}
+
 	zone.NewGlobal()
-	if _, err := tea.NewProgram(NewModel(), tea.WithAltScreen(), tea.WithMouseCellMotion()).Run(); err != nil {
+	if _, err := tea.NewProgram(NewModel(opts), tea.WithAltScreen(), tea.WithMouseCellMotion()).Run(); err != nil {
 		fmt.Printf("Could not start program :(\n%v\n", err)
 		os.Exit(1)
 	}
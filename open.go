@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bingoviewer/flasher"
+	"bingoviewer/picker"
+	"bingoviewer/store"
+	"errors"
+	"fmt"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+	"path/filepath"
+	"time"
+)
+
+// databaseOpenedMsg is sent when a database finished opening in the background.
+type databaseOpenedMsg struct {
+	seq int
+	db  *store.DB
+}
+
+// databaseOpenFailedMsg is sent when a database couldn't be opened in the background.
+type databaseOpenFailedMsg struct {
+	seq      int
+	path     string
+	snapshot bool
+	err      error
+}
+
+// opening tracks the database that is currently being opened, closing cancel stops waiting for its lock.
+type opening struct {
+	path     string
+	snapshot bool
+	started  time.Time
+	cancel   chan struct{}
+}
+
+// showFilePicker switches to the file browser, starting next to the open database if there is one.
+func (m Model) showFilePicker() (tea.Model, tea.Cmd) {
+	dir := "."
+	if m.DatabaseFile != "" {
+		dir = filepath.Dir(m.DatabaseFile)
+	}
+	m.picker = picker.New(dir)
+	m.picker.Width, m.picker.Height = m.pickerSize()
+	m.state = FilePicker
+	return m, nil
+}
+
+func (m Model) openDatabase(load string) (tea.Model, tea.Cmd) {
+	return m.startOpening(load, false)
+}
+
+// openSnapshot opens a point-in-time copy of a database that is locked by someone else.
+func (m Model) openSnapshot(load string) (tea.Model, tea.Cmd) {
+	return m.startOpening(load, true)
+}
+
+// startOpening opens the database in the background and shows a spinner until it is done,
+// a newer open or a cancel bumps openSeq so results that arrive late are discarded.
+func (m Model) startOpening(load string, snapshot bool) (tea.Model, tea.Cmd) {
+	m.openSeq++
+	m.opening = opening{
+		path:     load,
+		snapshot: snapshot,
+		started:  time.Now(),
+		cancel:   make(chan struct{}),
+	}
+	m.state = Opening
+	m.spinner = spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(logoStyle.Copy().PaddingLeft(0)))
+
+	seq := m.openSeq
+	opts := store.Options{
+		ReadOnly: m.readOnly,
+		Timeout:  m.openTimeout,
+		Cancel:   m.opening.cancel,
+	}
+	open := func() tea.Msg {
+		var db *store.DB
+		var err error
+		if snapshot {
+			db, err = store.OpenSnapshot(load, opts)
+		} else {
+			db, err = store.Open(load, opts)
+		}
+		if err != nil {
+			return databaseOpenFailedMsg{seq: seq, path: load, snapshot: snapshot, err: err}
+		}
+		return databaseOpenedMsg{seq: seq, db: db}
+	}
+	return m, tea.Batch(open, m.spinner.Tick)
+}
+
+// cancelOpening stops waiting for the lock and forgets about the open, a database that was opened
+// in the meantime is closed when it arrives.
+func (m Model) cancelOpening() (tea.Model, tea.Cmd) {
+	m.openSeq++
+	close(m.opening.cancel)
+	m.state = Normal
+	m.Error(fmt.Sprintf("Open database cancelled: %v", m.opening.path))
+	return m, m.ClearInfoAfter("3s")
+}
+
+func (m Model) databaseOpened(msg databaseOpenedMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.openSeq {
+		_ = msg.db.Close()
+		return m, nil
+	}
+	m.state = Normal
+	return m.useDatabase(msg.db)
+}
+
+// backgroundOptions are the options of opens nobody can cancel, they give up after OPEN_TIMEOUT
+// when --timeout 0 asks to wait for the lock until cancelled.
+func (m Model) backgroundOptions() store.Options {
+	opts := store.Options{ReadOnly: m.readOnly, Timeout: m.openTimeout}
+	if opts.Timeout == 0 {
+		opts.Timeout = OPEN_TIMEOUT
+	}
+	return opts
+}
+
+func (m Model) databaseOpenFailed(msg databaseOpenFailedMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.openSeq {
+		return m, nil
+	}
+	m.state = Normal
+	if errors.Is(msg.err, store.ErrLocked) && !msg.snapshot {
+		m.lockedFile = msg.path
+		return m, flasher.AskFlash(snapshotFlash, fmt.Sprintf(
+			"%v is locked by another process.\n\nOpen a read-only snapshot copy of it instead?", filepath.Base(msg.path)))
+	}
+	if msg.snapshot {
+		m.Error(fmt.Sprintf("Open snapshot failed: %v", msg.err))
+	} else {
+		m.Error(fmt.Sprintf("Open database failed: %v", msg.err))
+	}
+	return m, nil
+}
+
+func (m Model) RenderOpening() string {
+	what := "Opening"
+	if m.opening.snapshot {
+		what = "Copying snapshot of"
+	}
+	elapsed := time.Since(m.opening.started).Truncate(100 * time.Millisecond)
+	return fmt.Sprintf("%v %v %v... %v\n\n[esc] cancel", m.spinner.View(), what, m.opening.path, elapsed)
+}
+
+func (m Model) useDatabase(db *store.DB) (tea.Model, tea.Cmd) {
+	if m.driver != nil {
+		_ = m.driver.Close()
+	}
+	m.driver = db
+	m.DatabaseFile = db.Path
+	if db.IsSnapshot() {
+		m.DatabaseFile = db.Source
+	}
+	m.activeCollection = 0
+	m.showRecord = false
+	m.validations = map[string]*validation{}
+	m.sorted = map[string]*sortedRows{}
+	m.stopSort()
+	if m.search != nil {
+		m.search.stop()
+		m.search = nil
+	}
+	m.resetWatch()
+
+	colls, err := m.driver.GetCollections()
+	if err != nil {
+		m.Error(fmt.Sprintf("Failed to get collections: %v", err))
+		return m, nil
+	}
+	m.collections = colls
+	m.applyStartupCollection()
+	cmd := tea.Batch(m.loadCollection(), m.applyStartupRow())
+	if m.startup.Diff != "" {
+		cmd = tea.Batch(cmd, m.startDiff(m.startup.Diff))
+	}
+	m.startup = Options{}
+	if db.IsSnapshot() {
+		m.Success(fmt.Sprintf("Opened snapshot of %v", db.Source))
+	} else {
+		m.Success(fmt.Sprintf("Opened database: %v", db.Path))
+	}
+	return m, tea.Batch(cmd, m.ClearInfoAfter("3s"))
+}
+
+// applyStartupCollection selects the collection requested on the command line.
+func (m *Model) applyStartupCollection() {
+	if m.startup.Collection == "" {
+		return
+	}
+	for i, coll := range m.collections {
+		if coll == m.startup.Collection {
+			m.activeCollection = i
+			return
+		}
+	}
+	m.Error(fmt.Sprintf("Collection %q not found", m.startup.Collection))
+}
+
+// applyStartupRow moves the cursor to the row requested on the command line.
+func (m *Model) applyStartupRow() tea.Cmd {
+	if m.startup.Row == 0 {
+		return nil
+	}
+	return m.showRow(m.startup.Row - 1)
+}
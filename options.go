@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Options are the startup settings taken from the command line.
+type Options struct {
+	// DatabaseFile is opened as soon as the program starts, if set.
+	DatabaseFile string
+	// Collection is the tab that should be active once the database is opened.
+	Collection string
+	// Row is the 1-based row the cursor is placed on, 0 leaves it at the top.
+	Row int
+	// ReadOnly opens databases with a shared lock so a running service can keep its own.
+	ReadOnly bool
+	// Timeout is how long to wait for the database file lock.
+	Timeout time.Duration
+	// Flatten is how many levels of nested objects are split into parent.child columns, 0 shows them whole.
+	Flatten int
+	// Watch reloads the active collection whenever the database file changes.
+	Watch bool
+	// Diff is a database to compare the opened one with as soon as it is open.
+	Diff string
+}
+
+// parseArgs reads the command line, flags are allowed before and after the database path.
+func parseArgs(args []string) (Options, error) {
+	var opts Options
+	fs := flag.NewFlagSet("bingoviewer", flag.ContinueOnError)
+	fs.StringVar(&opts.Collection, "collection", "", "collection to show when the database is opened")
+	fs.IntVar(&opts.Row, "row", 0, "1-based row to place the cursor on")
+	fs.BoolVar(&opts.ReadOnly, "read-only", false, "open databases without taking the write lock")
+	fs.DurationVar(&opts.Timeout, "timeout", OPEN_TIMEOUT, "how long to wait for the database lock, 0 waits until cancelled with esc")
+	fs.IntVar(&opts.Flatten, "flatten", 0, "split nested objects into parent.child columns this many levels deep")
+	fs.BoolVar(&opts.Watch, "watch", false, "reload the active collection when the database file changes")
+	fs.StringVar(&opts.Diff, "diff", "", "compare the database with this one once it is open")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: bingoviewer [database] [--collection name] [--row n] [--read-only] [--timeout 5s] [--flatten n] [--watch] [--diff other]\n")
+		fmt.Fprintf(fs.Output(), "       bingoviewer <command> [arguments], see bingoviewer help\n\n")
+		fs.PrintDefaults()
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return opts, err
+	}
+
+	if len(positional) > 1 {
+		return opts, fmt.Errorf("expected a single database file, got %v", len(positional))
+	}
+	if opts.Row < 0 {
+		return opts, fmt.Errorf("row must be positive, got %v", opts.Row)
+	}
+	if opts.Flatten < 0 {
+		return opts, fmt.Errorf("flatten depth can't be negative, got %v", opts.Flatten)
+	}
+	if opts.Timeout < 0 {
+		return opts, fmt.Errorf("timeout can't be negative, got %v", opts.Timeout)
+	}
+	if opts.Diff != "" {
+		if len(positional) == 0 {
+			return opts, fmt.Errorf("--diff needs a database to compare with")
+		}
+		if _, err := os.Stat(opts.Diff); err != nil {
+			return opts, err
+		}
+	}
+	if len(positional) == 1 {
+		opts.DatabaseFile = positional[0]
+		if _, err := os.Stat(opts.DatabaseFile); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// parseInterspersed parses flags that may come before, between and after the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
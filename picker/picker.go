@@ -0,0 +1,291 @@
+package picker
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// boltMagic is the marker bbolt writes into the meta page at the start of every database file.
+const boltMagic uint32 = 0xED0CDAED
+
+// boltMagicOffset skips the page header (id, flags, count, overflow) in front of the meta page.
+const boltMagicOffset = 16
+
+type SelectMsg struct {
+	Path string
+}
+
+type CancelMsg struct{}
+
+func selectFile(path string) tea.Cmd {
+	return func() tea.Msg {
+		return SelectMsg{Path: path}
+	}
+}
+
+func cancel() tea.Msg {
+	return CancelMsg{}
+}
+
+type Entry struct {
+	Name    string
+	Path    string
+	IsDir   bool
+	IsBolt  bool
+	Size    int64
+	ModTime time.Time
+}
+
+type Model struct {
+	Dir     string
+	ShowAll bool
+	Width   int
+	Height  int
+	Err     error
+
+	entries []Entry
+	cursor  int
+	top     int
+}
+
+var (
+	headerStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#7ac0f1")).Bold(true)
+	dirStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("#7ac0f1"))
+	boltStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#55ff55"))
+	fileStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#cccccc"))
+	dimStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("#5f5f5f"))
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5555"))
+	selectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#000")).Background(lipgloss.Color("#7ac0f1"))
+)
+
+func New(dir string) Model {
+	p := Model{}
+	return p.Open(dir)
+}
+
+// IsBoltFile checks the header of the file for the bbolt meta page magic.
+func IsBoltFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	header := make([]byte, boltMagicOffset+4)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	magic := header[boltMagicOffset:]
+	return binary.LittleEndian.Uint32(magic) == boltMagic || binary.BigEndian.Uint32(magic) == boltMagic
+}
+
+// Open reads the directory and resets the cursor, errors are kept on the model so they can be shown.
+func (p Model) Open(dir string) Model {
+	abs, err := filepath.Abs(dir)
+	if err == nil {
+		dir = abs
+	}
+	p.Dir = dir
+	p.cursor = 0
+	p.top = 0
+	p.entries, p.Err = p.readDir(dir)
+	return p
+}
+
+func (p Model) readDir(dir string) ([]Entry, error) {
+	var entries []Entry
+	if parent := filepath.Dir(dir); parent != dir {
+		entries = append(entries, Entry{Name: "..", Path: parent, IsDir: true})
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return entries, err
+	}
+
+	var files []Entry
+	for _, de := range dirEntries {
+		path := filepath.Join(dir, de.Name())
+		// follow symlinks so linked directories and databases show up as what they point to
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		entry := Entry{
+			Name:    de.Name(),
+			Path:    path,
+			IsDir:   info.IsDir(),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		}
+		if !entry.IsDir {
+			entry.IsBolt = info.Mode().IsRegular() && IsBoltFile(path)
+			if !entry.IsBolt && !p.ShowAll {
+				continue
+			}
+		}
+		files = append(files, entry)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].IsDir != files[j].IsDir {
+			return files[i].IsDir
+		}
+		return strings.ToLower(files[i].Name) < strings.ToLower(files[j].Name)
+	})
+	return append(entries, files...), nil
+}
+
+// Selected returns the entry under the cursor.
+func (p Model) Selected() (Entry, bool) {
+	if p.cursor < 0 || p.cursor >= len(p.entries) {
+		return Entry{}, false
+	}
+	return p.entries[p.cursor], true
+}
+
+func (p Model) listHeight() int {
+	// header, current directory, blank line and the status line
+	h := p.Height - 4
+	if h < 1 {
+		return 1
+	}
+	return h
+}
+
+func (p *Model) moveCursor(delta int) {
+	p.cursor += delta
+	if p.cursor >= len(p.entries) {
+		p.cursor = len(p.entries) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	if p.cursor < p.top {
+		p.top = p.cursor
+	}
+	if p.cursor >= p.top+p.listHeight() {
+		p.top = p.cursor - p.listHeight() + 1
+	}
+}
+
+func (p Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	msgKey, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+
+	switch msgKey.String() {
+	case "up", "k":
+		p.moveCursor(-1)
+	case "down", "j":
+		p.moveCursor(1)
+	case "pgup":
+		p.moveCursor(-p.listHeight())
+	case "pgdown":
+		p.moveCursor(p.listHeight())
+	case "home", "g":
+		p.moveCursor(-len(p.entries))
+	case "end", "G":
+		p.moveCursor(len(p.entries))
+	case "left", "h", "backspace":
+		return p.Open(filepath.Dir(p.Dir)), nil
+	case "a":
+		p.ShowAll = !p.ShowAll
+		return p.Open(p.Dir), nil
+	case "esc", "q":
+		return p, cancel
+	case "right", "l", "enter":
+		entry, ok := p.Selected()
+		if !ok {
+			return p, nil
+		}
+		if entry.IsDir {
+			return p.Open(entry.Path), nil
+		}
+		if msgKey.String() == "enter" {
+			return p, selectFile(entry.Path)
+		}
+	}
+	return p, nil
+}
+
+// FormatSize shows a byte count in binary units, 1.5 KiB.
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func (p Model) View() string {
+	var b strings.Builder
+	filter := "bbolt databases"
+	if p.ShowAll {
+		filter = "all files"
+	}
+	b.WriteString(headerStyle.Render("Open Database") + dimStyle.Render(fmt.Sprintf(" (showing %v, [a] to toggle)", filter)) + "\n")
+	b.WriteString(p.Dir + "\n\n")
+
+	nameWidth := p.Width - 34
+	if nameWidth < 10 {
+		nameWidth = 10
+	}
+
+	end := p.top + p.listHeight()
+	if end > len(p.entries) {
+		end = len(p.entries)
+	}
+	for i := p.top; i < end; i++ {
+		entry := p.entries[i]
+		name := entry.Name
+		style := fileStyle
+		details := ""
+		switch {
+		case entry.IsDir:
+			name += string(filepath.Separator)
+			style = dirStyle
+		case entry.IsBolt:
+			style = boltStyle
+		}
+		if !entry.IsDir {
+			details = fmt.Sprintf("%10v  %v", FormatSize(entry.Size), entry.ModTime.Format("2006-01-02 15:04"))
+		}
+		if len(name) > nameWidth {
+			name = name[:nameWidth-1] + "…"
+		}
+		line := fmt.Sprintf("%-*v %v", nameWidth, name, details)
+		if i == p.cursor {
+			line = selectedStyle.Render(line)
+		} else {
+			line = style.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	switch {
+	case p.Err != nil:
+		b.WriteString(errorStyle.Render(p.Err.Error()))
+	case len(p.entries) == 0 || (len(p.entries) == 1 && p.entries[0].Name == ".."):
+		if p.ShowAll {
+			b.WriteString(dimStyle.Render("Empty directory"))
+		} else {
+			b.WriteString(dimStyle.Render("No databases in this directory"))
+		}
+	}
+	return b.String()
+}
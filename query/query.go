@@ -0,0 +1,542 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a compiled filter expression such as
+//
+//	age > 30 && status == "active" && tags contains "beta"
+//
+// Fields are looked up by name, nested objects can be reached with dots (profile.city).
+// Supported operators are ==, !=, <, <=, >, >=, contains, matches (regular expression),
+// &&, || and !, with parentheses for grouping. Numbers may have a fraction and an exponent (1e-5).
+type Query struct {
+	Source string
+	root   node
+}
+
+// Compile parses the expression, errors point at the offending position.
+func Compile(src string) (*Query, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %v at %v", t, t.pos)
+	}
+	return &Query{Source: src, root: root}, nil
+}
+
+// Match returns true if the document satisfies the expression.
+func (q *Query) Match(doc map[string]any) bool {
+	return truthy(q.root.eval(doc))
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+var wordOps = map[string]bool{
+	"contains": true,
+	"matches":  true,
+	"and":      true,
+	"or":       true,
+	"not":      true,
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case r == '"' || r == '\'':
+			start := i
+			var b strings.Builder
+			i++
+			for ; i < len(rs) && rs[i] != r; i++ {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					i++
+				}
+				b.WriteRune(rs[i])
+			}
+			if i >= len(rs) {
+				return nil, fmt.Errorf("unterminated string at %v", start)
+			}
+			i++
+			tokens = append(tokens, token{tokString, b.String(), start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			start := i
+			i = scanNumber(rs, i)
+			if i < len(rs) && isIdentRune(rs[i]) {
+				// 1.2.3 or 12abc
+				for i < len(rs) && isIdentRune(rs[i]) {
+					i++
+				}
+				return nil, fmt.Errorf("bad number %q at %v", string(rs[start:i]), start)
+			}
+			tokens = append(tokens, token{tokNumber, string(rs[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(rs) && isIdentRune(rs[i]) {
+				i++
+			}
+			text := string(rs[start:i])
+			if wordOps[strings.ToLower(text)] {
+				tokens = append(tokens, token{tokOp, strings.ToLower(text), start})
+			} else {
+				tokens = append(tokens, token{tokIdent, text, start})
+			}
+		default:
+			start := i
+			two := ""
+			if i+1 < len(rs) {
+				two = string(rs[i : i+2])
+			}
+			switch two {
+			case "==", "!=", "<=", ">=", "&&", "||":
+				tokens = append(tokens, token{tokOp, two, start})
+				i += 2
+				continue
+			}
+			switch r {
+			case '<', '>', '!', '=':
+				op := string(r)
+				if op == "=" {
+					op = "=="
+				}
+				tokens = append(tokens, token{tokOp, op, start})
+				i++
+			default:
+				return nil, fmt.Errorf("unexpected %q at %v", r, start)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(rs)}), nil
+}
+
+// isIdentRune reports whether r can be part of a field path, dots separate nested fields.
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+}
+
+// scanNumber returns the end of the number starting at i: an optional minus, digits, an optional
+// fraction and an optional exponent like 1e-5.
+func scanNumber(rs []rune, i int) int {
+	digits := func(i int) int {
+		for i < len(rs) && unicode.IsDigit(rs[i]) {
+			i++
+		}
+		return i
+	}
+	if rs[i] == '-' {
+		i++
+	}
+	i = digits(i)
+	if i+1 < len(rs) && rs[i] == '.' && unicode.IsDigit(rs[i+1]) {
+		i = digits(i + 1)
+	}
+	if i < len(rs) && (rs[i] == 'e' || rs[i] == 'E') {
+		j := i + 1
+		if j < len(rs) && (rs[j] == '+' || rs[j] == '-') {
+			j++
+		}
+		if j < len(rs) && unicode.IsDigit(rs[j]) {
+			i = digits(j)
+		}
+	}
+	return i
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) acceptOp(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.next()
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.acceptOp("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.acceptOp("!", "not"); ok {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	opTok := p.peek()
+	op, ok := p.acceptOp("==", "!=", "<", "<=", ">", ">=", "contains", "matches")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if op == "matches" {
+		lit, ok := right.(literalNode)
+		pattern, isString := lit.value.(string)
+		if !ok || !isString {
+			return nil, fmt.Errorf("matches at %v needs a string pattern", opTok.pos)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("bad pattern at %v: %v", opTok.pos, err)
+		}
+		return matchNode{left, re}, nil
+	}
+	return compareNode{op, left, right}, nil
+}
+
+func (p *parser) parseOperand() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("missing ) for ( at %v", t.pos)
+		}
+		return inner, nil
+	case tokString:
+		return literalNode{t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q at %v", t.text, t.pos)
+		}
+		return literalNode{f}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return literalNode{true}, nil
+		case "false":
+			return literalNode{false}, nil
+		case "null", "nil":
+			return literalNode{nil}, nil
+		}
+		return fieldNode{strings.Split(t.text, ".")}, nil
+	}
+	return nil, fmt.Errorf("expected a field or value, got %v at %v", t, t.pos)
+}
+
+type node interface {
+	eval(doc map[string]any) any
+}
+
+type literalNode struct {
+	value any
+}
+
+func (n literalNode) eval(map[string]any) any {
+	return n.value
+}
+
+type fieldNode struct {
+	path []string
+}
+
+func (n fieldNode) eval(doc map[string]any) any {
+	return Lookup(doc, n.path)
+}
+
+// Lookup follows the path through nested objects, the first step falls back to a
+// case-insensitive match so both the Go field name and the json name work.
+func Lookup(doc map[string]any, path []string) any {
+	var current any = doc
+	for i, part := range path {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v, ok := m[part]
+		if !ok && i == 0 {
+			for k, val := range m {
+				if strings.EqualFold(k, part) {
+					v, ok = val, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil
+		}
+		current = v
+	}
+	return current
+}
+
+type andNode struct {
+	left, right node
+}
+
+func (n andNode) eval(doc map[string]any) any {
+	return truthy(n.left.eval(doc)) && truthy(n.right.eval(doc))
+}
+
+type orNode struct {
+	left, right node
+}
+
+func (n orNode) eval(doc map[string]any) any {
+	return truthy(n.left.eval(doc)) || truthy(n.right.eval(doc))
+}
+
+type notNode struct {
+	inner node
+}
+
+func (n notNode) eval(doc map[string]any) any {
+	return !truthy(n.inner.eval(doc))
+}
+
+type matchNode struct {
+	left node
+	re   *regexp.Regexp
+}
+
+func (n matchNode) eval(doc map[string]any) any {
+	v := n.left.eval(doc)
+	if v == nil {
+		return false
+	}
+	if s, ok := v.(string); ok {
+		return n.re.MatchString(s)
+	}
+	return n.re.MatchString(fmt.Sprintf("%v", v))
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) eval(doc map[string]any) any {
+	l, r := n.left.eval(doc), n.right.eval(doc)
+	switch n.op {
+	case "==":
+		return equal(l, r)
+	case "!=":
+		return !equal(l, r)
+	case "contains":
+		return contains(l, r)
+	}
+
+	c, ok := compare(l, r)
+	if !ok {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// truthy decides whether a bare value used as a condition passes, missing fields and zero values don't.
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	case []any:
+		return len(v) > 0
+	case map[string]any:
+		return len(v) > 0
+	}
+	return true
+}
+
+func toNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func equal(l, r any) bool {
+	if l == nil || r == nil {
+		return l == nil && r == nil
+	}
+	if lf, ok := toNumber(l); ok {
+		rf, ok := toNumber(r)
+		return ok && lf == rf
+	}
+	switch l := l.(type) {
+	case string:
+		rs, ok := r.(string)
+		return ok && l == rs
+	case bool:
+		rb, ok := r.(bool)
+		return ok && l == rb
+	}
+	return fmt.Sprintf("%v", l) == fmt.Sprintf("%v", r)
+}
+
+// compare orders numbers numerically and strings lexically, RFC 3339 times sort correctly as strings.
+func compare(l, r any) (int, bool) {
+	if lf, ok := toNumber(l); ok {
+		rf, ok := toNumber(r)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case lf < rf:
+			return -1, true
+		case lf > rf:
+			return 1, true
+		}
+		return 0, true
+	}
+	ls, lok := l.(string)
+	rs, rok := r.(string)
+	if !lok || !rok {
+		return 0, false
+	}
+	return strings.Compare(ls, rs), true
+}
+
+func contains(l, r any) bool {
+	switch l := l.(type) {
+	case string:
+		rs, ok := r.(string)
+		if !ok {
+			rs = fmt.Sprintf("%v", r)
+		}
+		return strings.Contains(strings.ToLower(l), strings.ToLower(rs))
+	case []any:
+		for _, item := range l {
+			if equal(item, r) {
+				return true
+			}
+		}
+	case map[string]any:
+		if rs, ok := r.(string); ok {
+			_, found := l[rs]
+			return found
+		}
+	}
+	return false
+}
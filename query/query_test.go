@@ -0,0 +1,129 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+var doc = map[string]any{
+	"name":   "Ada Lovelace",
+	"age":    float64(36),
+	"score":  0.00001,
+	"active": true,
+	"tags":   []any{"math", "poetry"},
+	"nick":   nil,
+	"profile": map[string]any{
+		"city": "London",
+		"address": map[string]any{
+			"zip": "W1",
+		},
+	},
+	"Status": "retired",
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		// precedence: ! binds tighter than &&, which binds tighter than ||
+		{`active || age > 100 && age < 0`, true},
+		{`(active || age > 100) && age < 0`, false},
+		{`!active && age == 36`, false},
+		{`!(active && age == 40)`, true},
+		{`not active or age == 36`, true},
+		{`active and not nick`, true},
+		{`age > 30 && age <= 36 && name matches "^Ada"`, true},
+
+		// literals
+		{`name == "Ada Lovelace"`, true},
+		{`name == 'Ada Lovelace'`, true},
+		{`name contains "love"`, true},
+		{`name == "Ada \"Lovelace\""`, false},
+		{`age == 36`, true},
+		{`age == 3.6e1`, true},
+		{`age > -1`, true},
+		{`age>-1`, true},
+		{`score == 1e-5`, true},
+		{`score < 1E-4`, true},
+		{`score > 1e+3`, false},
+		{`active == true`, true},
+		{`active == false`, false},
+		{`nick == null`, true},
+		{`missing == nil`, true},
+		{`tags contains "poetry"`, true},
+		{`tags contains "prose"`, false},
+
+		// nested paths, the first step ignores case
+		{`profile.city == "London"`, true},
+		{`profile.address.zip == "W1"`, true},
+		{`profile.address.street == null`, true},
+		{`name.first == null`, true},
+		{`status == "retired"`, true},
+		{`profile contains "city"`, true},
+	}
+	for _, tt := range tests {
+		q, err := Compile(tt.src)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", tt.src, err)
+			continue
+		}
+		if got := q.Match(doc); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`age >`, "expected a field or value, got end of query at 5"},
+		{`(age > 1`, "missing ) for ( at 0"},
+		{`age > 1)`, `unexpected ")" at 7`},
+		{`name == "Ada`, "unterminated string at 8"},
+		{`age # 1`, "unexpected '#' at 4"},
+		{`created-at == 1`, "unexpected '-' at 7"},
+		{`age-1`, `unexpected "-1" at 3`},
+		{`age == 1.2.3`, `bad number "1.2.3" at 7`},
+		{`age == 12abc`, `bad number "12abc" at 7`},
+		{`name matches 5`, "matches at 5 needs a string pattern"},
+		{`name matches "("`, "bad pattern at 5"},
+		{`&& age`, `expected a field or value, got "&&" at 0`},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.src)
+		if err == nil {
+			t.Errorf("Compile(%q) should fail", tt.src)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%q) failed with %q, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestLex(t *testing.T) {
+	tokens, err := lex(`a.b >= -1.5e-3 || !c`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []token{
+		{tokIdent, "a.b", 0},
+		{tokOp, ">=", 4},
+		{tokNumber, "-1.5e-3", 7},
+		{tokOp, "||", 15},
+		{tokOp, "!", 18},
+		{tokIdent, "c", 19},
+		{tokEOF, "", 20},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("got %v tokens, want %v: %+v", len(tokens), len(want), tokens)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("token %v is %+v, want %+v", i, tokens[i], want[i])
+		}
+	}
+}
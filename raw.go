@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RAW_PREVIEW is how much of a value the raw browser shows, larger values are cut.
+const RAW_PREVIEW = 64 * 1024
+
+// rawMode is how the raw browser shows values, rawAuto picks JSON, UTF-8 or hex by what the value holds.
+type rawMode int
+
+const (
+	rawAuto rawMode = iota
+	rawText
+	rawJSON
+	rawHex
+)
+
+func (r rawMode) String() string {
+	switch r {
+	case rawText:
+		return "utf-8"
+	case rawJSON:
+		return "json"
+	case rawHex:
+		return "hex"
+	}
+	return "auto"
+}
+
+// rawBrowser walks the bbolt buckets of the open database as they are stored. path is the bucket
+// shown, cursors the cursor of every bucket above it so going back up lands where it left.
+type rawBrowser struct {
+	path     [][]byte
+	cursors  []int
+	entries  []store.RawEntry
+	cursor   int
+	err      error
+	value    []byte
+	valueErr error
+	mode     rawMode
+	// offset is how far the value is scrolled down
+	offset int
+}
+
+// showRaw opens the raw browser on the top level buckets.
+func (m Model) showRaw() (tea.Model, tea.Cmd) {
+	if m.driver == nil {
+		return m, nil
+	}
+	m.raw = rawBrowser{mode: m.raw.mode}
+	m.loadRawBucket()
+	m.state = RawBrowser
+	return m, nil
+}
+
+// loadRawBucket lists the bucket at the current path and reads the value under the cursor.
+func (m *Model) loadRawBucket() {
+	r := &m.raw
+	r.entries, r.err = m.driver.RawList(r.path)
+	r.cursor = max(0, min(r.cursor, len(r.entries)-1))
+	m.loadRawValue()
+}
+
+// loadRawValue reads the value of the key under the cursor, buckets have none.
+func (m *Model) loadRawValue() {
+	r := &m.raw
+	r.value, r.valueErr, r.offset = nil, nil, 0
+	if r.cursor >= len(r.entries) || r.entries[r.cursor].Bucket {
+		return
+	}
+	r.value, r.valueErr = m.driver.RawGet(r.path, r.entries[r.cursor].Key)
+}
+
+func (m Model) updateRaw(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	r := &m.raw
+	page := max(1, m.window.height-14)
+	moveTo := func(cursor int) {
+		cursor = max(0, min(cursor, len(r.entries)-1))
+		if cursor != r.cursor {
+			r.cursor = cursor
+			m.loadRawValue()
+		}
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Escape, m.keys.Raw):
+		m.state = Normal
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Up):
+		moveTo(r.cursor - 1)
+	case key.Matches(msg, m.keys.Down):
+		moveTo(r.cursor + 1)
+	case key.Matches(msg, m.keys.PgUp):
+		moveTo(r.cursor - page)
+	case key.Matches(msg, m.keys.PgDn):
+		moveTo(r.cursor + page)
+	case key.Matches(msg, m.keys.First):
+		moveTo(0)
+	case key.Matches(msg, m.keys.Last):
+		moveTo(len(r.entries) - 1)
+	case key.Matches(msg, m.keys.Enter, m.keys.Right):
+		if r.cursor >= len(r.entries) || !r.entries[r.cursor].Bucket {
+			break
+		}
+		r.path = append(r.path[:len(r.path):len(r.path)], r.entries[r.cursor].Key)
+		r.cursors = append(r.cursors, r.cursor)
+		r.cursor = 0
+		m.loadRawBucket()
+	case key.Matches(msg, m.keys.Left) || msg.String() == "backspace":
+		if len(r.path) == 0 {
+			break
+		}
+		r.path = r.path[:len(r.path)-1]
+		r.cursor = r.cursors[len(r.cursors)-1]
+		r.cursors = r.cursors[:len(r.cursors)-1]
+		m.loadRawBucket()
+	case msg.String() == "m":
+		r.mode = (r.mode + 1) % (rawHex + 1)
+		r.offset = 0
+	case msg.String() == "ctrl+d":
+		r.offset += page / 2
+	case msg.String() == "ctrl+u":
+		r.offset = max(0, r.offset-page/2)
+	case key.Matches(msg, m.keys.Copy):
+		m.copyRawValue()
+		return m, m.ClearInfoAfter("3s")
+	}
+	return m, nil
+}
+
+// copyRawValue puts the value under the cursor on the clipboard, as text when it is valid UTF-8 and as hex otherwise.
+func (m *Model) copyRawValue() {
+	r := m.raw
+	if r.value == nil {
+		return
+	}
+	name := rawName(r.entries[r.cursor].Key)
+	if utf8.Valid(r.value) {
+		termenv.Copy(string(r.value))
+		m.Success(fmt.Sprintf("Copied the value of %v to the clipboard", name))
+		return
+	}
+	termenv.Copy(hex.EncodeToString(r.value))
+	m.Success(fmt.Sprintf("Copied the value of %v to the clipboard as hex", name))
+}
+
+// rawName shows a key or bucket name as text when it is printable UTF-8 and as hex otherwise.
+func rawName(name []byte) string {
+	if utf8.Valid(name) && len(name) > 0 {
+		printable := true
+		for _, r := range string(name) {
+			if !unicode.IsPrint(r) {
+				printable = false
+				break
+			}
+		}
+		if printable {
+			return string(name)
+		}
+	}
+	return "0x" + hex.EncodeToString(name)
+}
+
+// rawValueLines renders a value in the given mode and returns the mode it was rendered in and a note
+// on what it couldn't show, a value that isn't valid JSON falls back to hex. Hex dumps get as many bytes
+// per line as fit in width.
+func rawValueLines(value []byte, mode rawMode, width int) (lines []string, note string, shown rawMode) {
+	if mode == rawAuto {
+		switch {
+		case json.Valid(value):
+			mode = rawJSON
+		case utf8.Valid(value):
+			mode = rawText
+		default:
+			mode = rawHex
+		}
+	}
+	if len(value) > RAW_PREVIEW {
+		note = fmt.Sprintf("showing the first %v of %v bytes", RAW_PREVIEW, len(value))
+		value = value[:RAW_PREVIEW]
+		if mode == rawJSON {
+			// a cut document is no JSON any more
+			mode = rawText
+		}
+	}
+
+	var text string
+	switch mode {
+	case rawJSON:
+		var b bytes.Buffer
+		if err := json.Indent(&b, value, "", "  "); err != nil {
+			lines, _, _ := rawValueLines(value, rawHex, width)
+			return lines, fmt.Sprintf("not JSON: %v", err), rawHex
+		}
+		text = b.String()
+	case rawText:
+		if !utf8.Valid(value) {
+			note = strings.TrimPrefix(note+", not valid UTF-8", ", ")
+		}
+		text = strings.Map(func(r rune) rune {
+			if r == '\n' || r == '\t' || unicode.IsPrint(r) {
+				return r
+			}
+			return '·'
+		}, strings.ToValidUTF8(string(value), "�"))
+		text = strings.ReplaceAll(text, "\t", "    ")
+	default:
+		return hexLines(value, width), note, mode
+	}
+	return strings.Split(text, "\n"), note, mode
+}
+
+// hexLines is a hex dump like hexdump -C, offset, bytes and their printable characters, 16 bytes
+// per line or fewer when they don't fit in width.
+func hexLines(value []byte, width int) []string {
+	perLine := 16
+	for perLine > 4 && 10+perLine*3+perLine/8+perLine+2 > width {
+		perLine /= 2
+	}
+	var lines []string
+	for offset := 0; offset < len(value); offset += perLine {
+		chunk := value[offset:min(offset+perLine, len(value))]
+		var b strings.Builder
+		fmt.Fprintf(&b, "%08x  ", offset)
+		for i := 0; i < perLine; i++ {
+			if i > 0 && i%8 == 0 {
+				b.WriteString(" ")
+			}
+			if i < len(chunk) {
+				fmt.Fprintf(&b, "%02x ", chunk[i])
+			} else {
+				b.WriteString("   ")
+			}
+		}
+		b.WriteString("|")
+		for _, c := range chunk {
+			if c >= 0x20 && c < 0x7f {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		b.WriteString("|")
+		lines = append(lines, b.String())
+	}
+	return lines
+}
+
+func (m Model) RenderRaw() string {
+	r := m.raw
+	var crumbs []string
+	for _, name := range r.path {
+		crumbs = append(crumbs, rawName(name))
+	}
+	title := fmt.Sprintf("Raw buckets of %v: %v", m.DatabaseFile, logoStyle.Copy().PaddingLeft(0).Render("/"+strings.Join(crumbs, "/")))
+	help := "[enter] open bucket    [left] back up    [m] value as auto/utf-8/json/hex    [ctrl+d/u] scroll value    [y] copy value    [esc] close"
+	if r.err != nil {
+		return fmt.Sprintf("%v\n\n%v\n\n%v", title, errorStyle.Render(r.err.Error()), help)
+	}
+
+	height := max(1, m.window.height-14)
+	listWidth := max(20, min(60, (m.window.width-6)*2/5))
+	valueWidth := max(20, m.window.width-6-listWidth-3)
+
+	var list []string
+	start := max(0, min(r.cursor-height/2, len(r.entries)-height))
+	end := min(len(r.entries), start+height)
+	for i := start; i < end; i++ {
+		e := r.entries[i]
+		name, size := rawName(e.Key), fmt.Sprintf("%vB key", len(e.Key))
+		style := lipgloss.NewStyle()
+		if e.Bucket {
+			name = "▸ " + name + "/"
+			style = logoStyle.Copy().PaddingLeft(0)
+		} else {
+			size = fmt.Sprintf("%vB key, %vB", len(e.Key), e.Size)
+		}
+		nameWidth := max(4, listWidth-len(size)-2)
+		line := padCell(name, nameWidth, style) + "  " + treePunctStyle.Render(size)
+		if i == r.cursor {
+			line = accentStyle.Copy().Padding(0).Render(padCell(name, nameWidth, lipgloss.NewStyle()) + "  " + size)
+		}
+		list = append(list, line)
+	}
+	if len(r.entries) == 0 {
+		list = append(list, treePunctStyle.Render("empty bucket"))
+	}
+
+	var value []string
+	switch {
+	case r.cursor >= len(r.entries):
+	case r.entries[r.cursor].Bucket:
+		value = []string{treePunctStyle.Render("nested bucket, enter to open it")}
+	case r.valueErr != nil:
+		value = []string{errorStyle.Render(r.valueErr.Error())}
+	default:
+		lines, note, mode := rawValueLines(r.value, r.mode, valueWidth)
+		label := mode.String()
+		if r.mode == rawAuto {
+			label = "auto: " + label
+		}
+		value = []string{logoStyle.Copy().PaddingLeft(0).Render(fmt.Sprintf("%v bytes as %v", len(r.value), label))}
+		if note != "" {
+			value = append(value, treePunctStyle.Render(padCell(note, valueWidth, lipgloss.NewStyle())))
+		}
+		value = append(value, "")
+		rows := max(1, height-len(value))
+		offset := max(0, min(r.offset, len(lines)-rows))
+		for _, line := range lines[offset:min(len(lines), offset+rows)] {
+			value = append(value, padCell(line, valueWidth, lipgloss.NewStyle()))
+		}
+	}
+
+	separator := strings.TrimSuffix(strings.Repeat(" │ \n", max(len(list), len(value))), "\n")
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		lipgloss.NewStyle().Width(listWidth).Render(strings.Join(list, "\n")),
+		treePunctStyle.Render(separator),
+		strings.Join(value, "\n"))
+	return fmt.Sprintf("%v\n\n%v\n\n%v", title, body, help)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bingoviewer/schema"
+	"bingoviewer/store"
+	"encoding/json"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nokusukun/bingo"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SCHEMA_SAMPLE is how many of the newest documents the schema panel scans until it is asked to scan all of them.
+const SCHEMA_SAMPLE = 5000
+
+// schemaScannedMsg carries the report of a scan running in the background.
+type schemaScannedMsg struct {
+	seq    int
+	report schema.Report
+	err    error
+}
+
+// schemaPanel is the schema report of the active collection, exportInput asks where to write the JSON Schema.
+type schemaPanel struct {
+	collection  string
+	seq         int
+	scanning    bool
+	report      *schema.Report
+	err         error
+	viewport    viewport.Model
+	exporting   bool
+	exportInput textinput.Model
+}
+
+// scanSchema reads the newest limit documents of the collection in the background, 0 reads all of them.
+func scanSchema(db *store.DB, collection string, limit int, seq int) tea.Cmd {
+	return func() tea.Msg {
+		report, err := scanCollection(db, collection, limit)
+		return schemaScannedMsg{seq: seq, report: report, err: err}
+	}
+}
+
+// scanCollection reports the fields of the newest limit documents of the collection, 0 reads all of them.
+func scanCollection(db *store.DB, collection string, limit int) (schema.Report, error) {
+	s := schema.NewScanner()
+	err := store.Find(db, collection, bingo.Query[kmap]{Count: limit}, func(_ []byte, doc *kmap) error {
+		s.Add(*doc)
+		return nil
+	})
+	report := s.Report(collection)
+	if limit > 0 && report.Docs == limit {
+		total, countErr := db.Count(collection)
+		report.Sampled = countErr != nil || total > limit
+	}
+	return report, err
+}
+
+// showSchema opens the schema panel and scans a sample of the active collection.
+func (m Model) showSchema() (tea.Model, tea.Cmd) {
+	if m.driver == nil || len(m.collections) == 0 {
+		return m, nil
+	}
+	m.schemaView = schemaPanel{
+		collection: m.collections[m.activeCollection],
+		seq:        m.schemaView.seq + 1,
+		scanning:   true,
+		viewport:   viewport.New(m.window.width-4, m.window.height-11),
+	}
+	m.state = SchemaPanel
+	return m, scanSchema(m.driver, m.schemaView.collection, SCHEMA_SAMPLE, m.schemaView.seq)
+}
+
+func (m Model) schemaScanned(msg schemaScannedMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.schemaView.seq {
+		return m, nil
+	}
+	m.schemaView.scanning = false
+	m.schemaView.err = msg.err
+	m.schemaView.report = &msg.report
+	m.schemaView.viewport.SetContent(m.renderSchemaReport(m.schemaView.viewport.Width))
+	return m, nil
+}
+
+func (m Model) updateSchemaPanel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	panel := &m.schemaView
+	if panel.exporting {
+		switch msg.String() {
+		case "esc":
+			panel.exporting = false
+			panel.exportInput.Blur()
+			return m, nil
+		case "enter":
+			path := strings.TrimSpace(panel.exportInput.Value())
+			if path == "" {
+				return m, nil
+			}
+			panel.exporting = false
+			panel.exportInput.Blur()
+			return m.exportSchema(path)
+		}
+		var cmd tea.Cmd
+		panel.exportInput, cmd = panel.exportInput.Update(msg)
+		return m, cmd
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Escape, m.keys.Schema):
+		m.state = Normal
+		// a scan still running is dropped
+		panel.seq++
+		return m, nil
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case msg.String() == "a":
+		if panel.scanning || panel.report == nil || !panel.report.Sampled {
+			return m, nil
+		}
+		panel.seq++
+		panel.scanning = true
+		return m, scanSchema(m.driver, panel.collection, 0, panel.seq)
+	case key.Matches(msg, m.keys.Export):
+		if panel.report == nil {
+			return m, nil
+		}
+		panel.exportInput = textinput.New()
+		panel.exportInput.Prompt = "export JSON Schema to: "
+		panel.exportInput.SetValue(panel.collection + ".schema.json")
+		panel.exportInput.CursorEnd()
+		panel.exporting = true
+		return m, panel.exportInput.Focus()
+	}
+	var cmd tea.Cmd
+	panel.viewport, cmd = panel.viewport.Update(msg)
+	return m, cmd
+}
+
+// exportSchema writes the report of the panel as a JSON Schema.
+func (m Model) exportSchema(path string) (tea.Model, tea.Cmd) {
+	data, err := json.MarshalIndent(m.schemaView.report.JSONSchema(), "", "  ")
+	if err == nil {
+		err = os.WriteFile(path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		m.Error(fmt.Sprintf("Export failed: %v", err))
+		return m, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	m.Success(fmt.Sprintf("Exported the schema of %v to %v", m.schemaView.collection, abs))
+	return m, m.ClearInfoAfter("5s")
+}
+
+// describeScan says which documents a report was built from.
+func describeScan(r *schema.Report) string {
+	if r.Sampled {
+		return fmt.Sprintf("the newest %v documents", r.Docs)
+	}
+	return fmt.Sprintf("all %v documents", r.Docs)
+}
+
+var schemaHeader = []string{"FIELD", "TYPES", "PRESENT", "NULL", "DISTINCT", "MIN", "MAX", "EXAMPLES"}
+
+// schemaRows formats a line per field of the report.
+func schemaRows(r *schema.Report) [][]string {
+	var rows [][]string
+	for _, f := range r.Fields {
+		rows = append(rows, []string{
+			f.Path,
+			f.TypeNames(),
+			fmt.Sprintf("%.0f%%", 100*f.Presence()),
+			fmt.Sprintf("%.0f%%", 100*f.NullRatio()),
+			fmt.Sprint(f.Distinct),
+			schemaBound(f.Min),
+			schemaBound(f.Max),
+			strings.Join(f.Examples, ", "),
+		})
+	}
+	return rows
+}
+
+// renderSchemaReport lays the fields of the report out as a table, fields seen with
+// more than one type are highlighted since that is usually drift between app versions.
+func (m Model) renderSchemaReport(width int) string {
+	r := m.schemaView.report
+	if len(r.Fields) == 0 {
+		return "No fields"
+	}
+	rows := append([][]string{schemaHeader}, schemaRows(r)...)
+	widths := make([]int, len(schemaHeader))
+	for _, row := range rows {
+		for i, cell := range row[:len(row)-1] {
+			widths[i] = max(widths[i], len([]rune(cell)))
+		}
+	}
+	used := 0
+	for _, w := range widths[:len(widths)-1] {
+		used += w + 2
+	}
+	widths[len(widths)-1] = max(10, width-used)
+
+	var b strings.Builder
+	for i, row := range rows {
+		var line strings.Builder
+		for j, cell := range row {
+			if runes := []rune(cell); len(runes) > widths[j] {
+				cell = string(runes[:widths[j]-1]) + "…"
+			}
+			padded := cell + strings.Repeat(" ", max(0, widths[j]-len([]rune(cell)))+2)
+			switch {
+			case i == 0:
+				padded = logoStyle.Copy().PaddingLeft(0).Render(padded)
+			case j == 1 && r.Fields[i-1].Mixed():
+				padded = diffRemoveStyle.Render(padded)
+			}
+			line.WriteString(padded)
+		}
+		b.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+	return b.String()
+}
+
+// schemaBound shortens numbers and times for the min and max columns.
+func schemaBound(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return fmt.Sprintf("%.6g", v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func (m Model) RenderSchemaPanel() string {
+	panel := m.schemaView
+	title := fmt.Sprintf("Schema of %v", logoStyle.Copy().PaddingLeft(0).Render(panel.collection))
+	switch {
+	case panel.scanning:
+		return fmt.Sprintf("%v\n\nScanning...", title)
+	case panel.err != nil:
+		return fmt.Sprintf("%v\n\n%v", title, errorStyle.Render(panel.err.Error()))
+	}
+	// laid out again for the current window size, the scroll position is kept
+	panel.viewport.Width = m.window.width - 4
+	panel.viewport.Height = m.window.height - 11
+	panel.viewport.SetContent(m.renderSchemaReport(panel.viewport.Width))
+	help := "[x] export JSON Schema    [esc] close"
+	if panel.report.Sampled {
+		help = "[a] scan all    " + help
+	}
+	return fmt.Sprintf("%v from %v\n\n%v\n\n%v", title, describeScan(panel.report), panel.viewport.View(), help)
+}
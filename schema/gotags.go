@@ -0,0 +1,351 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goField is a field of a Go struct as it is stored in a document.
+type goField struct {
+	name string
+	// validate is the validate tag of the field, checked with go-playground/validator
+	validate string
+	typ      *goType
+}
+
+// goType is the JSON shape of a Go type, kind is a JSON Schema type or empty for any value.
+type goType struct {
+	kind   string
+	format string
+	elem   *goType
+	fields []goField
+	// pointer types are nil when the value is missing or null
+	pointer bool
+}
+
+// goStruct checks documents against the validate tags and field types of a Go struct.
+type goStruct struct {
+	root     *goType
+	validate *validator.Validate
+}
+
+// ParseGoStruct reads the struct named typeName from Go source, the package clause may be left out.
+// Without a name the struct with a Key method is used, or the first struct of the source.
+func ParseGoStruct(src []byte, typeName string) (Checker, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		// generated structs are printed without a package clause
+		var withPackage error
+		file, withPackage = parser.ParseFile(fset, "", append([]byte("package p\n\n"), src...), parser.SkipObjectResolution)
+		if withPackage != nil {
+			return nil, err
+		}
+	}
+
+	specs := map[string]*ast.TypeSpec{}
+	var structs []string
+	hasKey := map[string]bool{}
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if ts, ok := spec.(*ast.TypeSpec); ok {
+					specs[ts.Name.Name] = ts
+					if _, ok := ts.Type.(*ast.StructType); ok {
+						structs = append(structs, ts.Name.Name)
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Name.Name == "Key" && decl.Recv != nil && len(decl.Recv.List) == 1 {
+				recv := decl.Recv.List[0].Type
+				if star, ok := recv.(*ast.StarExpr); ok {
+					recv = star.X
+				}
+				if ident, ok := recv.(*ast.Ident); ok {
+					hasKey[ident.Name] = true
+				}
+			}
+		}
+	}
+
+	if typeName == "" {
+		for _, name := range structs {
+			if hasKey[name] {
+				typeName = name
+				break
+			}
+		}
+	}
+	if typeName == "" && len(structs) > 0 {
+		typeName = structs[0]
+	}
+	spec, ok := specs[typeName]
+	if !ok {
+		if typeName == "" {
+			return nil, errors.New("no struct found")
+		}
+		return nil, fmt.Errorf("struct %v not found", typeName)
+	}
+	if _, ok := spec.Type.(*ast.StructType); !ok {
+		return nil, fmt.Errorf("%v is not a struct", typeName)
+	}
+
+	r := &typeResolver{specs: specs, resolving: map[string]bool{}}
+	g := &goStruct{root: r.resolve(spec.Type), validate: validator.New()}
+	if err := g.checkTags(g.root, ""); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// checkTags tries every validate tag once, validator panics on tags it doesn't know.
+func (g *goStruct) checkTags(typ *goType, path string) (err error) {
+	for _, f := range typ.fields {
+		fieldPath := f.name
+		if path != "" {
+			fieldPath = path + "." + f.name
+		}
+		if f.validate != "" {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						err = fmt.Errorf("invalid validate tag on %v: %v", fieldPath, r)
+					}
+				}()
+				_ = g.validate.Var(nil, f.validate)
+			}()
+			if err != nil {
+				return err
+			}
+		}
+		if f.typ.fields != nil {
+			if err := g.checkTags(f.typ, fieldPath); err != nil {
+				return err
+			}
+		} else if f.typ.elem != nil && f.typ.elem.fields != nil {
+			if err := g.checkTags(f.typ.elem, fieldPath+"[]"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// typeResolver turns the types of the parsed file into their JSON shape.
+type typeResolver struct {
+	specs map[string]*ast.TypeSpec
+	// resolving guards against types that contain themselves
+	resolving map[string]bool
+}
+
+func (r *typeResolver) resolve(expr ast.Expr) *goType {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return &goType{kind: "string"}
+		case "bool":
+			return &goType{kind: "boolean"}
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune":
+			return &goType{kind: "integer"}
+		case "float32", "float64":
+			return &goType{kind: "number"}
+		}
+		spec, ok := r.specs[t.Name]
+		if !ok || r.resolving[t.Name] {
+			return &goType{}
+		}
+		r.resolving[t.Name] = true
+		defer delete(r.resolving, t.Name)
+		return r.resolve(spec.Type)
+	case *ast.StarExpr:
+		typ := *r.resolve(t.X)
+		typ.pointer = true
+		return &typ
+	case *ast.ArrayType:
+		elem := r.resolve(t.Elt)
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			// encoding/json writes byte slices as base64 strings
+			return &goType{kind: "string"}
+		}
+		return &goType{kind: "array", elem: elem}
+	case *ast.MapType:
+		return &goType{kind: "object"}
+	case *ast.SelectorExpr:
+		if pkg, ok := t.X.(*ast.Ident); ok {
+			switch pkg.Name + "." + t.Sel.Name {
+			case "time.Time":
+				return &goType{kind: "string", format: "date-time"}
+			case "json.Number":
+				return &goType{kind: "number"}
+			}
+		}
+		return &goType{}
+	case *ast.StructType:
+		typ := &goType{kind: "object"}
+		for _, field := range t.Fields.List {
+			tag := reflect.StructTag("")
+			if field.Tag != nil {
+				if unquoted, err := strconv.Unquote(field.Tag.Value); err == nil {
+					tag = reflect.StructTag(unquoted)
+				}
+			}
+			jsonName, _, _ := strings.Cut(tag.Get("json"), ",")
+			if jsonName == "-" {
+				continue
+			}
+			fieldType := r.resolve(field.Type)
+			if len(field.Names) == 0 {
+				// embedded structs are written inline unless they are given a name
+				if jsonName == "" && fieldType.kind == "object" && fieldType.fields != nil {
+					typ.fields = append(typ.fields, fieldType.fields...)
+					continue
+				}
+				jsonName = embeddedName(field.Type)
+			}
+			for _, name := range field.Names {
+				if !name.IsExported() {
+					continue
+				}
+				fieldName := jsonName
+				if fieldName == "" {
+					fieldName = name.Name
+				}
+				typ.fields = append(typ.fields, goField{name: fieldName, validate: tag.Get("validate"), typ: fieldType})
+			}
+			if len(field.Names) == 0 && jsonName != "" {
+				typ.fields = append(typ.fields, goField{name: jsonName, validate: tag.Get("validate"), typ: fieldType})
+			}
+		}
+		return typ
+	}
+	return &goType{}
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.StarExpr:
+		return embeddedName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	}
+	return ""
+}
+
+func (g *goStruct) Check(doc map[string]any) []Violation {
+	var out []Violation
+	g.checkObject(g.root, doc, "", &out)
+	return out
+}
+
+func (g *goStruct) checkObject(typ *goType, doc map[string]any, path string, out *[]Violation) {
+	for _, f := range typ.fields {
+		fieldPath := f.name
+		if path != "" {
+			fieldPath = path + "." + f.name
+		}
+		value := doc[f.name]
+		if f.validate != "" {
+			g.checkTag(f.validate, value, f.typ, fieldPath, out)
+		}
+		if value != nil {
+			g.checkValue(f.typ, value, fieldPath, out)
+		}
+	}
+}
+
+// checkValue checks that a value has the JSON shape of the Go type, null is always accepted.
+func (g *goStruct) checkValue(typ *goType, value any, path string, out *[]Violation) {
+	if value == nil || typ.kind == "" {
+		return
+	}
+	actual := TypeOf(value)
+	if actual != typ.kind && !(typ.kind == "number" && actual == "integer") {
+		*out = append(*out, Violation{Path: path, Rule: "type", Message: fmt.Sprintf("expected %v, got %v", typ.kind, actual)})
+		return
+	}
+	switch v := value.(type) {
+	case string:
+		if typ.format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+				*out = append(*out, Violation{Path: path, Rule: "type", Message: fmt.Sprintf("%v is not an RFC 3339 time", short(v))})
+			}
+		}
+	case []any:
+		for i, e := range v {
+			g.checkValue(typ.elem, e, fmt.Sprintf("%v[%v]", path, i), out)
+		}
+	case map[string]any:
+		if typ.fields != nil {
+			g.checkObject(typ, v, path, out)
+		}
+	}
+}
+
+// zero is the value a missing or null field decodes to, the one its validate tag sees in Go.
+func (t *goType) zero() any {
+	if t.pointer {
+		return nil
+	}
+	switch t.kind {
+	case "string":
+		return ""
+	case "integer", "number":
+		return 0.0
+	case "boolean":
+		return false
+	case "array":
+		return []any(nil)
+	case "object":
+		return map[string]any(nil)
+	}
+	return nil
+}
+
+// checkTag runs the validate tag of a field on its value, missing fields are checked as the zero value
+// of their type. Pointers are passed as pointers so omitempty only skips nil.
+func (g *goStruct) checkTag(tag string, value any, typ *goType, path string, out *[]Violation) {
+	checked := value
+	switch {
+	case value == nil:
+		checked = typ.zero()
+	case typ.pointer:
+		checked = &value
+	}
+	err := g.validate.Var(checked, tag)
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		for _, fe := range errs {
+			rule := fe.Tag()
+			if fe.Param() != "" {
+				rule += "=" + fe.Param()
+			}
+			message := fmt.Sprintf("%v fails %v", short(fe.Value()), rule)
+			if value == nil {
+				message = "missing"
+			}
+			// dive reports the element as [i]
+			elem := ""
+			if strings.HasPrefix(fe.Namespace(), "[") {
+				elem = fe.Namespace()
+			}
+			*out = append(*out, Violation{Path: path + elem, Rule: rule, Message: message})
+		}
+		return
+	}
+	if err != nil {
+		*out = append(*out, Violation{Path: path, Rule: tag, Message: err.Error()})
+	}
+}
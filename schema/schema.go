@@ -0,0 +1,382 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MAX_EXAMPLES is how many distinct example values are kept per field.
+const MAX_EXAMPLES = 3
+
+// sketchSize is how many hashes the distinct value estimate keeps, counts below it are exact.
+const sketchSize = 256
+
+// Scanner collects statistics about the fields of the documents added to it.
+type Scanner struct {
+	docs int
+	root *node
+}
+
+// node holds the statistics of one path of the documents, children are the fields of the objects
+// seen there and elem the values of the arrays.
+type node struct {
+	path    string
+	present int
+	types   map[string]int
+	times   int
+
+	minNumber, maxNumber float64
+	minTime, maxTime     time.Time
+	examples             []string
+	sketch               sketch
+
+	order    []string
+	children map[string]*node
+	elem     *node
+}
+
+func newNode(path string) *node {
+	return &node{path: path, types: map[string]int{}, children: map[string]*node{}}
+}
+
+func NewScanner() *Scanner {
+	return &Scanner{root: newNode("")}
+}
+
+// Add records the fields of a document.
+func (s *Scanner) Add(doc map[string]any) {
+	s.docs++
+	s.root.add(doc)
+}
+
+// Docs returns how many documents were added.
+func (s *Scanner) Docs() int {
+	return s.docs
+}
+
+func (n *node) child(name string) *node {
+	c, ok := n.children[name]
+	if !ok {
+		path := name
+		if n.path != "" {
+			path = n.path + "." + name
+		}
+		c = newNode(path)
+		n.children[name] = c
+		n.order = append(n.order, name)
+	}
+	return c
+}
+
+func (n *node) add(v any) {
+	n.present++
+	t := TypeOf(v)
+	n.types[t]++
+	switch v := v.(type) {
+	case map[string]any:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			n.child(name).add(v[name])
+		}
+		return
+	case []any:
+		if n.elem == nil {
+			n.elem = newNode(n.path + "[]")
+		}
+		for _, e := range v {
+			n.elem.add(e)
+		}
+		return
+	case nil:
+		return
+	}
+
+	if f, ok := number(v); ok {
+		if n.types["integer"]+n.types["number"] == 1 || f < n.minNumber {
+			n.minNumber = f
+		}
+		if n.types["integer"]+n.types["number"] == 1 || f > n.maxNumber {
+			n.maxNumber = f
+		}
+	}
+	if s, ok := v.(string); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			n.times++
+			if n.times == 1 || ts.Before(n.minTime) {
+				n.minTime = ts
+			}
+			if n.times == 1 || ts.After(n.maxTime) {
+				n.maxTime = ts
+			}
+		}
+	}
+
+	repr := fmt.Sprintf("%v", v)
+	n.sketch.add(t + ":" + repr)
+	if len(n.examples) < MAX_EXAMPLES {
+		example, _ := json.Marshal(v)
+		s := string(example)
+		for _, e := range n.examples {
+			if e == s {
+				return
+			}
+		}
+		n.examples = append(n.examples, s)
+	}
+}
+
+// TypeOf returns the JSON Schema type of a decoded JSON value, numbers without a fraction are integers.
+func TypeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	case json.Number:
+		if strings.ContainsAny(v.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+func number(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Field is the report of a single path, nested fields are written with dots and array elements with [].
+type Field struct {
+	Path string `json:"path"`
+	// Types counts the values of each JSON type, null included
+	Types map[string]int `json:"types"`
+	// Present counts the values seen, Parents the objects the field could have been in
+	Present int `json:"present"`
+	Parents int `json:"parents"`
+	Nulls   int `json:"nulls"`
+	// Distinct estimates the number of distinct scalar values, it is exact below 256
+	Distinct int      `json:"distinct"`
+	Min      any      `json:"min,omitempty"`
+	Max      any      `json:"max,omitempty"`
+	Examples []string `json:"examples,omitempty"`
+}
+
+// Presence is the share of the parent objects the field was present in.
+func (f Field) Presence() float64 {
+	if f.Parents == 0 {
+		return 0
+	}
+	return float64(f.Present) / float64(f.Parents)
+}
+
+// NullRatio is the share of the values that were null.
+func (f Field) NullRatio() float64 {
+	if f.Present == 0 {
+		return 0
+	}
+	return float64(f.Nulls) / float64(f.Present)
+}
+
+// Mixed reports if the field was seen with values of more than one type besides null,
+// integers and numbers count as one type.
+func (f Field) Mixed() bool {
+	kinds := 0
+	for t := range f.Types {
+		if t != "null" && !(t == "integer" && f.Types["number"] > 0) {
+			kinds++
+		}
+	}
+	return kinds > 1
+}
+
+// TypeNames lists the types seen with the share of the values, the most common first.
+func (f Field) TypeNames() string {
+	var types []string
+	for t := range f.Types {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if f.Types[types[i]] != f.Types[types[j]] {
+			return f.Types[types[i]] > f.Types[types[j]]
+		}
+		return types[i] < types[j]
+	})
+	var parts []string
+	for _, t := range types {
+		parts = append(parts, fmt.Sprintf("%v %.0f%%", t, 100*float64(f.Types[t])/float64(f.Present)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Report is the result of a scan.
+type Report struct {
+	Collection string  `json:"collection"`
+	Docs       int     `json:"documents"`
+	Sampled    bool    `json:"sampled"`
+	Fields     []Field `json:"fields"`
+
+	root *node
+}
+
+// Report returns the statistics of every path seen so far, parents come before their fields.
+func (s *Scanner) Report(collection string) Report {
+	r := Report{Collection: collection, Docs: s.docs, root: s.root}
+	var walk func(n *node)
+	walk = func(n *node) {
+		for _, name := range n.order {
+			c := n.children[name]
+			// a field could have been in every object seen at its parent
+			r.Fields = append(r.Fields, c.field(n.types["object"]))
+			walk(c)
+		}
+		if n.elem != nil {
+			if n.elem.present > 0 {
+				r.Fields = append(r.Fields, n.elem.field(n.elem.present))
+			}
+			walk(n.elem)
+		}
+	}
+	walk(s.root)
+	return r
+}
+
+func (n *node) field(parents int) Field {
+	f := Field{
+		Path:     n.path,
+		Types:    map[string]int{},
+		Present:  n.present,
+		Parents:  parents,
+		Nulls:    n.types["null"],
+		Distinct: n.sketch.estimate(),
+		Examples: n.examples,
+	}
+	for t, count := range n.types {
+		f.Types[t] = count
+	}
+	switch {
+	case n.types["integer"]+n.types["number"] > 0:
+		f.Min, f.Max = n.minNumber, n.maxNumber
+	case n.times > 0:
+		f.Min, f.Max = n.minTime.Format(time.RFC3339Nano), n.maxTime.Format(time.RFC3339Nano)
+	}
+	return f
+}
+
+// JSONSchema describes the scanned documents as a JSON Schema, fields present in every object
+// are required and strings that were always RFC 3339 times get the date-time format.
+func (r Report) JSONSchema() map[string]any {
+	s := r.root.schema()
+	s["type"] = "object"
+	out := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       r.Collection,
+		"description": fmt.Sprintf("Inferred from %v document(s)", r.Docs),
+	}
+	for k, v := range s {
+		out[k] = v
+	}
+	return out
+}
+
+func (n *node) schema() map[string]any {
+	s := map[string]any{}
+	var types []string
+	for _, t := range []string{"string", "number", "integer", "boolean", "object", "array", "null"} {
+		if n.types[t] == 0 || (t == "integer" && n.types["number"] > 0) {
+			continue
+		}
+		types = append(types, t)
+	}
+	switch len(types) {
+	case 0:
+	case 1:
+		s["type"] = types[0]
+	default:
+		s["type"] = types
+	}
+	if n.types["string"] > 0 && n.times == n.types["string"] {
+		s["format"] = "date-time"
+	}
+	if len(n.order) > 0 {
+		props := map[string]any{}
+		var required []string
+		for _, name := range n.order {
+			c := n.children[name]
+			props[name] = c.schema()
+			if c.present >= n.types["object"] && c.present > 0 {
+				required = append(required, name)
+			}
+		}
+		s["properties"] = props
+		if len(required) > 0 {
+			s["required"] = required
+		}
+	}
+	if n.elem != nil && n.elem.present > 0 {
+		s["items"] = n.elem.schema()
+	}
+	return s
+}
+
+// sketch estimates the number of distinct values from the smallest hashes seen (k minimum values).
+type sketch struct {
+	hashes []uint64
+}
+
+func (s *sketch) add(v string) {
+	h := fnv.New64a()
+	h.Write([]byte(v))
+	// fnv alone leaves similar strings close together, the finalizer of splitmix64 spreads them out
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	x ^= x >> 31
+	if len(s.hashes) == sketchSize && x >= s.hashes[sketchSize-1] {
+		return
+	}
+	i := sort.Search(len(s.hashes), func(i int) bool { return s.hashes[i] >= x })
+	if i < len(s.hashes) && s.hashes[i] == x {
+		return
+	}
+	s.hashes = append(s.hashes, 0)
+	copy(s.hashes[i+1:], s.hashes[i:])
+	s.hashes[i] = x
+	if len(s.hashes) > sketchSize {
+		s.hashes = s.hashes[:sketchSize]
+	}
+}
+
+func (s *sketch) estimate() int {
+	if len(s.hashes) < sketchSize {
+		return len(s.hashes)
+	}
+	kth := float64(s.hashes[sketchSize-1]) / math.MaxUint64
+	return int(float64(sketchSize-1) / kth)
+}
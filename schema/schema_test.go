@@ -0,0 +1,79 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSketch(t *testing.T) {
+	var s sketch
+	for i := 0; i < 3*sketchSize; i++ {
+		// repeated values don't count twice
+		s.add(fmt.Sprint(i % 100))
+	}
+	if got := s.estimate(); got != 100 {
+		t.Fatalf("estimate of 100 values is %v, expected it exact below %v", got, sketchSize)
+	}
+
+	for _, n := range []int{1000, 20000, 200000} {
+		var s sketch
+		for i := 0; i < n; i++ {
+			s.add(fmt.Sprintf("user-%v", i))
+		}
+		// the standard error of k minimum values is about 1/sqrt(k), 6% for 256 hashes
+		got := s.estimate()
+		if ratio := float64(got) / float64(n); ratio < 0.8 || ratio > 1.2 {
+			t.Errorf("estimate of %v values is %v", n, got)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	s := NewScanner()
+	s.Add(map[string]any{"name": "ann", "age": 30.0, "seen": "2024-01-02T03:04:05Z", "tags": []any{"a", "b"}})
+	s.Add(map[string]any{"name": "bob", "age": 41.5, "seen": "2024-03-01T00:00:00Z", "profile": map[string]any{"city": "Oslo"}})
+	s.Add(map[string]any{"name": nil, "age": "unknown", "seen": "2023-12-31T23:00:00Z"})
+	r := s.Report("users")
+
+	fields := map[string]Field{}
+	var paths []string
+	for _, f := range r.Fields {
+		fields[f.Path] = f
+		paths = append(paths, f.Path)
+	}
+	// parents come before their fields
+	want := []string{"age", "name", "seen", "tags", "tags[]", "profile", "profile.city"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths %v, want %v", paths, want)
+	}
+
+	age := fields["age"]
+	if age.Min != 30.0 || age.Max != 41.5 || !age.Mixed() || age.Distinct != 3 {
+		t.Errorf("age: min %v max %v mixed %v distinct %v", age.Min, age.Max, age.Mixed(), age.Distinct)
+	}
+	if name := fields["name"]; name.Nulls != 1 || name.Presence() != 1 || name.Mixed() {
+		t.Errorf("name: %+v", name)
+	}
+	if seen := fields["seen"]; seen.Min != "2023-12-31T23:00:00Z" || seen.Max != "2024-03-01T00:00:00Z" {
+		t.Errorf("seen: min %v max %v", seen.Min, seen.Max)
+	}
+	if tags := fields["tags"]; tags.Present != 1 || tags.Parents != 3 {
+		t.Errorf("tags: present %v of %v", tags.Present, tags.Parents)
+	}
+	if elems := fields["tags[]"]; elems.Present != 2 || elems.Types["string"] != 2 {
+		t.Errorf("tags[]: %+v", elems)
+	}
+
+	js := r.JSONSchema()
+	if !reflect.DeepEqual(js["required"], []string{"age", "name", "seen"}) {
+		t.Errorf("required %v", js["required"])
+	}
+	props := js["properties"].(map[string]any)
+	if seen := props["seen"].(map[string]any); seen["format"] != "date-time" {
+		t.Errorf("seen: %v", seen)
+	}
+	if age := props["age"].(map[string]any); !reflect.DeepEqual(age["type"], []string{"string", "number"}) {
+		t.Errorf("age: %v", age)
+	}
+}
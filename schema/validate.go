@@ -0,0 +1,402 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Violation is a rule a document breaks, Path is the offending field written with dots and [i] for array elements.
+type Violation struct {
+	Path    string `json:"path"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Field returns the top level field of the path, the column the violation shows up in.
+func (v Violation) Field() string {
+	if i := strings.IndexAny(v.Path, ".["); i >= 0 {
+		return v.Path[:i]
+	}
+	return v.Path
+}
+
+// Checker checks documents against a schema.
+type Checker interface {
+	Check(doc map[string]any) []Violation
+}
+
+// Load reads the schema at path, a Go file with a struct carrying validate tags or else a JSON Schema.
+// A Go file can name the struct to use after a colon, file.go:User, otherwise the struct with a Key
+// method is used or the first one of the file.
+func Load(path string) (Checker, error) {
+	typeName := ""
+	if i := strings.LastIndex(path, ".go:"); i >= 0 {
+		path, typeName = path[:i+3], path[i+4:]
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".go") {
+		return ParseGoStruct(data, typeName)
+	}
+	return ParseJSONSchema(data)
+}
+
+// jsonSchema checks documents against a JSON Schema. The keywords understood are type, enum, const,
+// the number, string, array and object constraints, allOf, anyOf, oneOf, not and local $refs.
+type jsonSchema struct {
+	root     any
+	patterns map[string]*regexp.Regexp
+}
+
+// ParseJSONSchema reads a JSON Schema, the patterns in it are compiled up front.
+func ParseJSONSchema(data []byte) (Checker, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON Schema: %w", err)
+	}
+	switch root.(type) {
+	case map[string]any, bool:
+	default:
+		return nil, fmt.Errorf("invalid JSON Schema: expected an object, got %v", TypeOf(root))
+	}
+	s := &jsonSchema{root: root, patterns: map[string]*regexp.Regexp{}}
+	if err := s.compilePatterns(root); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *jsonSchema) compilePatterns(v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		if p, ok := v["pattern"].(string); ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+			s.patterns[p] = re
+		}
+		for _, child := range v {
+			if err := s.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if err := s.compilePatterns(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *jsonSchema) Check(doc map[string]any) []Violation {
+	var out []Violation
+	s.check(s.root, doc, "", &out, 0)
+	return out
+}
+
+// maxRefDepth stops schemas that refer to themselves without ever reaching a value.
+const maxRefDepth = 64
+
+func (s *jsonSchema) check(sch any, v any, path string, out *[]Violation, depth int) {
+	fail := func(rule, format string, a ...any) {
+		*out = append(*out, Violation{Path: path, Rule: rule, Message: fmt.Sprintf(format, a...)})
+	}
+	if b, ok := sch.(bool); ok {
+		if !b {
+			fail("false", "no value is allowed here")
+		}
+		return
+	}
+	rules, ok := sch.(map[string]any)
+	if !ok {
+		return
+	}
+
+	if ref, ok := rules["$ref"].(string); ok {
+		target, err := s.resolve(ref)
+		switch {
+		case err != nil:
+			fail("$ref", "%v", err)
+		case depth >= maxRefDepth:
+			fail("$ref", "%v refers to itself too deeply", ref)
+		default:
+			s.check(target, v, path, out, depth+1)
+		}
+	}
+
+	if t, ok := rules["type"]; ok && !typeMatches(t, v) {
+		fail("type", "expected %v, got %v", describeTypes(t), TypeOf(v))
+		// the other keywords would only repeat the mismatch
+		return
+	}
+	if enum, ok := rules["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("enum", "%v is not one of %v", short(v), short(enum))
+		}
+	}
+	if c, ok := rules["const"]; ok && !reflect.DeepEqual(c, v) {
+		fail("const", "expected %v, got %v", short(c), short(v))
+	}
+
+	switch v := v.(type) {
+	case float64:
+		s.checkNumber(rules, v, fail)
+	case string:
+		s.checkString(rules, v, fail)
+	case []any:
+		s.checkArray(rules, v, path, out, depth, fail)
+	case map[string]any:
+		s.checkObject(rules, v, path, out, depth, fail)
+	}
+
+	if all, ok := rules["allOf"].([]any); ok {
+		for _, sub := range all {
+			s.check(sub, v, path, out, depth+1)
+		}
+	}
+	if anyOf, ok := rules["anyOf"].([]any); ok {
+		if s.matching(anyOf, v, depth) == 0 {
+			fail("anyOf", "doesn't match any of the %v schemas", len(anyOf))
+		}
+	}
+	if oneOf, ok := rules["oneOf"].([]any); ok {
+		if n := s.matching(oneOf, v, depth); n != 1 {
+			fail("oneOf", "matches %v of the %v schemas, expected exactly one", n, len(oneOf))
+		}
+	}
+	if not, ok := rules["not"]; ok {
+		var sub []Violation
+		s.check(not, v, path, &sub, depth+1)
+		if len(sub) == 0 {
+			fail("not", "matches a schema it must not match")
+		}
+	}
+}
+
+// matching counts the schemas v is valid against.
+func (s *jsonSchema) matching(schemas []any, v any, depth int) int {
+	n := 0
+	for _, sub := range schemas {
+		var violations []Violation
+		s.check(sub, v, "", &violations, depth+1)
+		if len(violations) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *jsonSchema) checkNumber(rules map[string]any, v float64, fail func(rule, format string, a ...any)) {
+	if min, ok := rules["minimum"].(float64); ok && v < min {
+		fail("minimum", "%v is less than %v", v, min)
+	}
+	if max, ok := rules["maximum"].(float64); ok && v > max {
+		fail("maximum", "%v is greater than %v", v, max)
+	}
+	if min, ok := rules["exclusiveMinimum"].(float64); ok && v <= min {
+		fail("exclusiveMinimum", "%v is not greater than %v", v, min)
+	}
+	if max, ok := rules["exclusiveMaximum"].(float64); ok && v >= max {
+		fail("exclusiveMaximum", "%v is not less than %v", v, max)
+	}
+	if m, ok := rules["multipleOf"].(float64); ok && m > 0 {
+		if q := v / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("multipleOf", "%v is not a multiple of %v", v, m)
+		}
+	}
+}
+
+func (s *jsonSchema) checkString(rules map[string]any, v string, fail func(rule, format string, a ...any)) {
+	length := utf8.RuneCountInString(v)
+	if min, ok := rules["minLength"].(float64); ok && float64(length) < min {
+		fail("minLength", "%v characters, expected at least %v", length, min)
+	}
+	if max, ok := rules["maxLength"].(float64); ok && float64(length) > max {
+		fail("maxLength", "%v characters, expected at most %v", length, max)
+	}
+	if p, ok := rules["pattern"].(string); ok && !s.patterns[p].MatchString(v) {
+		fail("pattern", "%v doesn't match %v", short(v), p)
+	}
+	if f, ok := rules["format"].(string); ok && !formatMatches(f, v) {
+		fail("format", "%v is not a valid %v", short(v), f)
+	}
+}
+
+func (s *jsonSchema) checkArray(rules map[string]any, v []any, path string, out *[]Violation, depth int, fail func(rule, format string, a ...any)) {
+	if min, ok := rules["minItems"].(float64); ok && float64(len(v)) < min {
+		fail("minItems", "%v items, expected at least %v", len(v), min)
+	}
+	if max, ok := rules["maxItems"].(float64); ok && float64(len(v)) > max {
+		fail("maxItems", "%v items, expected at most %v", len(v), max)
+	}
+	if unique, _ := rules["uniqueItems"].(bool); unique {
+	outer:
+		for i := range v {
+			for j := 0; j < i; j++ {
+				if reflect.DeepEqual(v[i], v[j]) {
+					fail("uniqueItems", "items %v and %v are equal", j, i)
+					break outer
+				}
+			}
+		}
+	}
+	prefix, _ := rules["prefixItems"].([]any)
+	for i, item := range v {
+		itemPath := fmt.Sprintf("%v[%v]", path, i)
+		if i < len(prefix) {
+			s.check(prefix[i], item, itemPath, out, depth+1)
+			continue
+		}
+		if items, ok := rules["items"]; ok {
+			s.check(items, item, itemPath, out, depth+1)
+		}
+	}
+}
+
+func (s *jsonSchema) checkObject(rules map[string]any, v map[string]any, path string, out *[]Violation, depth int, fail func(rule, format string, a ...any)) {
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+	if required, ok := rules["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, ok := v[name]; !ok {
+				*out = append(*out, Violation{Path: join(name), Rule: "required", Message: "missing"})
+			}
+		}
+	}
+	if min, ok := rules["minProperties"].(float64); ok && float64(len(v)) < min {
+		fail("minProperties", "%v fields, expected at least %v", len(v), min)
+	}
+	if max, ok := rules["maxProperties"].(float64); ok && float64(len(v)) > max {
+		fail("maxProperties", "%v fields, expected at most %v", len(v), max)
+	}
+
+	props, _ := rules["properties"].(map[string]any)
+	additional, hasAdditional := rules["additionalProperties"]
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if sub, ok := props[name]; ok {
+			s.check(sub, v[name], join(name), out, depth+1)
+			continue
+		}
+		if !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			*out = append(*out, Violation{Path: join(name), Rule: "additionalProperties", Message: "field is not in the schema"})
+			continue
+		}
+		s.check(additional, v[name], join(name), out, depth+1)
+	}
+}
+
+// resolve follows a reference within the schema like #/$defs/address.
+func (s *jsonSchema) resolve(ref string) (any, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("only references within the schema are supported, got %v", ref)
+	}
+	target := s.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		obj, ok := target.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("can't resolve %v", ref)
+		}
+		if target, ok = obj[part]; !ok {
+			return nil, fmt.Errorf("can't resolve %v", ref)
+		}
+	}
+	return target, nil
+}
+
+func typeMatches(t any, v any) bool {
+	switch t := t.(type) {
+	case string:
+		actual := TypeOf(v)
+		return actual == t || (t == "number" && actual == "integer")
+	case []any:
+		for _, option := range t {
+			if typeMatches(option, v) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func describeTypes(t any) string {
+	if list, ok := t.([]any); ok {
+		var names []string
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// formatMatches checks the formats that are common in documents, unknown formats always match.
+func formatMatches(format, v string) bool {
+	var err error
+	switch format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339Nano, v)
+	case "date":
+		_, err = time.Parse(time.DateOnly, v)
+	case "time":
+		_, err = time.Parse("15:04:05Z07:00", v)
+	case "email":
+		_, err = mail.ParseAddress(v)
+	case "uuid":
+		return uuidPattern.MatchString(v)
+	}
+	return err == nil
+}
+
+// short formats a value for a message, long values are cut off.
+func short(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if s := []rune(string(data)); len(s) > 40 {
+		return string(s[:39]) + "…"
+	}
+	return string(data)
+}
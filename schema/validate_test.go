@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func rules(violations []Violation) []string {
+	var out []string
+	for _, v := range violations {
+		out = append(out, v.Path+" "+v.Rule)
+	}
+	return out
+}
+
+func TestJSONSchema(t *testing.T) {
+	checker, err := ParseJSONSchema([]byte(`{
+		"type": "object",
+		"required": ["name", "age"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 2, "pattern": "^[a-z]+$"},
+			"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+			"email": {"type": "string", "format": "email"},
+			"role": {"enum": ["admin", "user"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true, "maxItems": 3},
+			"address": {"$ref": "#/$defs/address"},
+			"id": {"oneOf": [{"type": "integer"}, {"type": "string", "format": "uuid"}]}
+		},
+		"$defs": {
+			"address": {"type": "object", "required": ["city"], "properties": {"city": {"type": "string"}}}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		doc  string
+		want []string
+	}{
+		{`{"name": "ann", "age": 30, "email": "ann@example.com", "role": "admin", "tags": ["a", "b"],
+			"address": {"city": "Oslo"}, "id": "123e4567-e89b-12d3-a456-426614174000"}`, nil},
+		{`{"name": "ann"}`, []string{"age required"}},
+		{`{"name": "A", "age": 30}`, []string{"name minLength", "name pattern"}},
+		{`{"name": "ann", "age": 30.5}`, []string{"age type"}},
+		{`{"name": "ann", "age": 150}`, []string{"age exclusiveMaximum"}},
+		{`{"name": "ann", "age": -1, "email": "nope"}`, []string{"age minimum", "email format"}},
+		{`{"name": "ann", "age": 1, "role": "root"}`, []string{"role enum"}},
+		{`{"name": "ann", "age": 1, "tags": ["a", 2, "a", "b"]}`, []string{"tags maxItems", "tags uniqueItems", "tags[1] type"}},
+		{`{"name": "ann", "age": 1, "address": {"zip": "0150"}}`, []string{"address.city required"}},
+		{`{"name": "ann", "age": 1, "id": "not-a-uuid"}`, []string{"id oneOf"}},
+		{`{"name": "ann", "age": 1, "extra": true}`, []string{"extra additionalProperties"}},
+	}
+	for _, tt := range tests {
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if got := rules(checker.Check(doc)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestParseJSONSchemaErrors(t *testing.T) {
+	for _, src := range []string{`[]`, `{"pattern": "("}`, `{"type": `} {
+		if _, err := ParseJSONSchema([]byte(src)); err == nil {
+			t.Errorf("%v: expected an error", src)
+		}
+	}
+}
+
+// a schema referring to itself stops instead of recursing forever
+func TestJSONSchemaRefLoop(t *testing.T) {
+	checker, err := ParseJSONSchema([]byte(`{"$ref": "#"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checker.Check(map[string]any{"a": 1.0})
+}
+
+func TestGoStruct(t *testing.T) {
+	checker, err := ParseGoStruct([]byte(`
+type User struct {
+	Name    string    `+"`json:\"name\" validate:\"required,min=2\"`"+`
+	Age     int       `+"`json:\"age\" validate:\"gte=0\"`"+`
+	Tags    []string  `+"`json:\"tags\" validate:\"dive,oneof=a b\"`"+`
+	Created time.Time `+"`json:\"created\"`"+`
+	Score   *int      `+"`json:\"score\" validate:\"omitempty,gte=1\"`"+`
+}
+`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		doc  map[string]any
+		want []string
+	}{
+		{map[string]any{"name": "ann", "age": 3.0, "tags": []any{"a"}, "created": "2024-01-02T03:04:05Z"}, nil},
+		{map[string]any{"age": -1.0}, []string{"name required", "age gte=0"}},
+		{map[string]any{"name": "ann", "age": "3", "tags": []any{"a", "c"}}, []string{"age type", "tags[1] oneof=a b"}},
+		{map[string]any{"name": "ann", "created": "yesterday"}, []string{"created type"}},
+		{map[string]any{"name": "ann", "score": 0.0}, []string{"score gte=1"}},
+	}
+	for _, tt := range tests {
+		if got := rules(checker.Check(tt.doc)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.doc, got, tt.want)
+		}
+	}
+}
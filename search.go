@@ -0,0 +1,385 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"errors"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/nokusukun/bingo"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MAX_SEARCH_HITS is where a search stops, a pattern matching more than that is too broad to be useful.
+const MAX_SEARCH_HITS = 1000
+
+// errSearchCancelled stops the scan of a search that was replaced or found enough.
+var errSearchCancelled = errors.New("search cancelled")
+
+// searchHit is a document holding the pattern, path is the first field it was found in.
+type searchHit struct {
+	collection string
+	key        []byte
+	path       string
+	text       string
+}
+
+// searchResultsMsg carries the hits found since the last one, done is set once every collection was scanned.
+type searchResultsMsg struct {
+	seq     int
+	hits    []searchHit
+	scanned int
+	done    bool
+	err     error
+}
+
+// search is the search running in the background or its results once it is done.
+type search struct {
+	seq      int
+	pattern  string
+	hits     []searchHit
+	scanned  int
+	done     bool
+	err      error
+	results  chan searchResultsMsg
+	cancel   chan struct{}
+	canceled bool
+}
+
+// stop ends the scan, the results found so far are kept.
+func (s *search) stop() {
+	if !s.done && !s.canceled {
+		s.canceled = true
+		close(s.cancel)
+	}
+}
+
+// compileSearch turns the input into a matcher, /.../ is a regular expression and anything else
+// a substring that is matched ignoring case.
+func compileSearch(pattern string) (func(string) bool, error) {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	needle := strings.ToLower(pattern)
+	return func(s string) bool {
+		return strings.Contains(strings.ToLower(s), needle)
+	}, nil
+}
+
+// matchValue looks for the pattern in a value and everything nested in it, it returns
+// the path and text of the first scalar that matches.
+func matchValue(v any, path string, match func(string) bool) (string, string, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			if p, text, ok := matchValue(v[k], childPath, match); ok {
+				return p, text, true
+			}
+		}
+	case kmap:
+		return matchValue(map[string]any(v), path, match)
+	case []any:
+		for i, e := range v {
+			if p, text, ok := matchValue(e, fmt.Sprintf("%v[%v]", path, i), match); ok {
+				return p, text, true
+			}
+		}
+	case nil:
+	default:
+		text := fmt.Sprint(v)
+		if f, ok := v.(float64); ok {
+			text = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+		if match(text) {
+			return path, text, true
+		}
+	}
+	return "", "", false
+}
+
+// runSearch scans every collection for documents holding the pattern and sends the hits in batches.
+func runSearch(db *store.DB, collections []string, match func(string) bool, s *search) {
+	defer close(s.results)
+	send := func(msg searchResultsMsg) bool {
+		msg.seq = s.seq
+		select {
+		case s.results <- msg:
+			return true
+		case <-s.cancel:
+			return false
+		}
+	}
+
+	var pending []searchHit
+	var failed []string
+	found, scanned := 0, 0
+	for _, collection := range collections {
+		err := store.Find(db, collection, bingo.Query[kmap]{}, func(key []byte, doc *kmap) error {
+			scanned++
+			path, text := KEY_COLUMN, string(key)
+			ok := match(text)
+			if !ok {
+				path, text, ok = matchValue(*doc, "", match)
+			}
+			if ok {
+				pending = append(pending, searchHit{collection: collection, key: append([]byte{}, key...), path: path, text: text})
+				found++
+			}
+			if found >= MAX_SEARCH_HITS {
+				return errSearchCancelled
+			}
+			if len(pending) >= 50 || scanned%1000 == 0 {
+				if !send(searchResultsMsg{hits: pending, scanned: scanned}) {
+					return errSearchCancelled
+				}
+				pending = nil
+			}
+			return nil
+		})
+		if errors.Is(err, errSearchCancelled) {
+			if found >= MAX_SEARCH_HITS {
+				break
+			}
+			return
+		}
+		if err != nil {
+			// a collection that can't be read doesn't keep the others from being searched
+			failed = append(failed, fmt.Sprintf("%v: %v", collection, err))
+		}
+	}
+	var err error
+	if len(failed) > 0 {
+		err = fmt.Errorf("%v collection(s) couldn't be searched, %v", len(failed), strings.Join(failed, "; "))
+	}
+	send(searchResultsMsg{hits: pending, scanned: scanned, done: true, err: err})
+}
+
+func waitSearchResults(ch chan searchResultsMsg) tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+func (m Model) showSearchPrompt() (tea.Model, tea.Cmd) {
+	if m.driver == nil {
+		return m, nil
+	}
+	m.searchInput = textinput.New()
+	m.searchInput.Prompt = "search every collection: "
+	m.searchInput.Placeholder = "text, or /regex/"
+	if m.search != nil {
+		m.searchInput.SetValue(m.search.pattern)
+		m.searchInput.CursorEnd()
+	}
+	m.state = SearchPrompt
+	return m, m.searchInput.Focus()
+}
+
+func (m Model) updateSearchPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		m.state = Normal
+		m.searchInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		pattern := m.searchInput.Value()
+		if strings.TrimSpace(pattern) == "" {
+			return m, nil
+		}
+		m.searchInput.Blur()
+		// the same search again shows the results it already found
+		if m.search != nil && m.search.pattern == pattern && !m.search.canceled {
+			m.state = SearchPanel
+			return m, nil
+		}
+		match, err := compileSearch(pattern)
+		if err != nil {
+			m.Error(fmt.Sprintf("Invalid search: %v", err))
+			return m, nil
+		}
+		return m.startSearch(pattern, match)
+	}
+	var cmd tea.Cmd
+	m.searchInput, cmd = m.searchInput.Update(msg)
+	return m, cmd
+}
+
+// startSearch replaces the previous search with a new one running in the background.
+func (m Model) startSearch(pattern string, match func(string) bool) (tea.Model, tea.Cmd) {
+	seq := 1
+	if m.search != nil {
+		m.search.stop()
+		seq = m.search.seq + 1
+	}
+	m.search = &search{
+		seq:     seq,
+		pattern: pattern,
+		results: make(chan searchResultsMsg),
+		cancel:  make(chan struct{}),
+	}
+	m.searchCursor = 0
+	m.state = SearchPanel
+
+	db, collections, s := m.driver, append([]string{}, m.collections...), m.search
+	go runSearch(db, collections, match, s)
+	return m, waitSearchResults(s.results)
+}
+
+func (m Model) searchResults(msg searchResultsMsg) (tea.Model, tea.Cmd) {
+	s := m.search
+	if s == nil || msg.seq != s.seq {
+		return m, nil
+	}
+	s.hits = append(s.hits, msg.hits...)
+	s.scanned = msg.scanned
+	if !msg.done {
+		return m, waitSearchResults(s.results)
+	}
+	s.done = true
+	s.err = msg.err
+	if msg.err != nil {
+		m.Error(fmt.Sprintf("Search incomplete: %v", msg.err))
+	}
+	return m, nil
+}
+
+// lines groups the hits by collection, in the order of the tabs.
+func (s *search) lines(collections []string) []searchHit {
+	var lines []searchHit
+	for _, collection := range collections {
+		for _, hit := range s.hits {
+			if hit.collection == collection {
+				lines = append(lines, hit)
+			}
+		}
+	}
+	return lines
+}
+
+func (m Model) updateSearchPanel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := m.search
+	lines := s.lines(m.collections)
+	page := max(1, m.window.height-14)
+
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		// a search still running goes on, f and enter show it again
+		m.state = Normal
+	case key.Matches(msg, m.keys.Search):
+		return m.showSearchPrompt()
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Up):
+		m.searchCursor = max(0, m.searchCursor-1)
+	case key.Matches(msg, m.keys.Down):
+		m.searchCursor = min(len(lines)-1, m.searchCursor+1)
+	case key.Matches(msg, m.keys.PgUp):
+		m.searchCursor = max(0, m.searchCursor-page)
+	case key.Matches(msg, m.keys.PgDn):
+		m.searchCursor = min(len(lines)-1, m.searchCursor+page)
+	case key.Matches(msg, m.keys.Enter):
+		if m.searchCursor < 0 || m.searchCursor >= len(lines) {
+			break
+		}
+		return m.openHit(lines[m.searchCursor])
+	}
+	return m, nil
+}
+
+// openHit shows the document of a hit in the document view with the cursor on the field that matched.
+func (m Model) openHit(hit searchHit) (tea.Model, tea.Cmd) {
+	m.state = Normal
+	var cmd tea.Cmd
+	if m.collections[m.activeCollection] != hit.collection {
+		for i, collection := range m.collections {
+			if collection == hit.collection {
+				m.activeCollection = i
+				cmd = m.loadCollection()
+				break
+			}
+		}
+	}
+	return m, tea.Batch(cmd, m.jumpTo(rowJump{
+		key:      hit.key,
+		path:     hit.path,
+		document: true,
+		missing:  fmt.Sprintf("%v isn't in the table, it doesn't match the current filter", string(hit.key)),
+	}))
+}
+
+func (m Model) RenderSearchPanel() string {
+	s := m.search
+	status := fmt.Sprintf("%v hit(s) in %v document(s)", len(s.hits), s.scanned)
+	switch {
+	case s.err != nil:
+		// the hits of the collections that could be read are still listed
+		status += ", " + errorStyle.Render(s.err.Error())
+	case !s.done:
+		status += ", searching..."
+	case len(s.hits) >= MAX_SEARCH_HITS:
+		status += fmt.Sprintf(", stopped at %v", MAX_SEARCH_HITS)
+	}
+	title := fmt.Sprintf("Searching for %v\n%v", logoStyle.Copy().PaddingLeft(0).Render(s.pattern), status)
+	help := "[enter] open document    [f] search again    [esc] close"
+	lines := s.lines(m.collections)
+	if len(lines) == 0 {
+		return fmt.Sprintf("%v\n\n%v", title, help)
+	}
+
+	height := max(1, m.window.height-14)
+	start := max(0, min(m.searchCursor-height/2, len(lines)-height))
+	end := min(len(lines), start+height)
+	keyWidth, pathWidth := 0, 0
+	for _, hit := range lines[start:end] {
+		keyWidth = max(keyWidth, len(hit.key))
+		pathWidth = max(pathWidth, len(hit.path))
+	}
+	textWidth := max(10, m.window.width-keyWidth-pathWidth-14)
+	format := fmt.Sprintf("  %%-%vv  %%-%vv  %%v", keyWidth, pathWidth)
+
+	var b strings.Builder
+	for i := start; i < end; i++ {
+		hit := lines[i]
+		// every collection starts with a header, repeated at the top when the list scrolled into it
+		if i == start || lines[i-1].collection != hit.collection {
+			count := 0
+			for _, h := range lines {
+				if h.collection == hit.collection {
+					count++
+				}
+			}
+			b.WriteString(logoStyle.Copy().PaddingLeft(0).Render(fmt.Sprintf("%v (%v)", hit.collection, count)) + "\n")
+		}
+		text := strings.ReplaceAll(hit.text, "\n", " ")
+		if runes := []rune(text); len(runes) > textWidth {
+			text = string(runes[:textWidth-1]) + "…"
+		}
+		line := fmt.Sprintf(format, string(hit.key), hit.path, text)
+		if i == m.searchCursor {
+			line = accentStyle.Copy().Padding(0).Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	return fmt.Sprintf("%v\n\n%v\n%v", title, b.String(), help)
+}
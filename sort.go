@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"bytes"
+	"errors"
+	"fmt"
+	stick "github.com/76creates/stickers"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nokusukun/bingo"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sortKey orders the table by one column, columns are remembered by name so they survive reloads.
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// activeSort returns the sort keys of the active collection, the first one is the primary key.
+func (m Model) activeSort() []sortKey {
+	if len(m.collections) == 0 {
+		return nil
+	}
+	return m.sorts[m.collections[m.activeCollection]]
+}
+
+// toggleSort cycles the column through ascending, descending and unsorted. Without add the column
+// becomes the only sort key, with add it is kept next to the existing keys as a secondary key.
+func (m *Model) toggleSort(column int, add bool) tea.Cmd {
+	if m.driver == nil || len(m.collections) == 0 || column < 0 || column >= len(m.columns) {
+		return nil
+	}
+	collection := m.collections[m.activeCollection]
+	name := m.columns[column][0]
+	keys := m.sorts[collection]
+
+	found := -1
+	for i, k := range keys {
+		if k.column == name {
+			found = i
+			break
+		}
+	}
+
+	switch {
+	case found < 0 && add:
+		keys = append(keys, sortKey{column: name})
+	case found < 0:
+		keys = []sortKey{{column: name}}
+	case !keys[found].desc:
+		if !add {
+			keys = []sortKey{keys[found]}
+			found = 0
+		}
+		keys[found].desc = true
+	default:
+		keys = append(keys[:found:found], keys[found+1:]...)
+		if !add {
+			keys = nil
+		}
+	}
+
+	current, _ := m.cursorKey()
+	if len(keys) == 0 {
+		delete(m.sorts, collection)
+		m.stopSort()
+		m.Info(fmt.Sprintf("Sorting cleared, %v is back in collection order", collection))
+		return m.reloadRows(current)
+	}
+	m.sorts[collection] = keys
+	if cmd := m.ensureSort(); cmd != nil {
+		// the rows keep their order until the sort is done, the headers show the new sort already
+		m.rebuildTable()
+		m.Info(fmt.Sprintf("Sorting by %v...", describeSort(keys)))
+		return cmd
+	}
+	m.Info(fmt.Sprintf("Sorted by %v", describeSort(keys)))
+	return m.reloadRows(current)
+}
+
+func describeSort(keys []sortKey) string {
+	var parts []string
+	for _, k := range keys {
+		dir := "asc"
+		if k.desc {
+			dir = "desc"
+		}
+		parts = append(parts, fmt.Sprintf("%v %v", k.column, dir))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sortedRows is the order of a sorted collection, the keys of all its rows. signature tells the sort
+// keys, query and columns it was sorted for, stale is set when the collection changed since.
+type sortedRows struct {
+	signature string
+	keys      [][]byte
+	stale     bool
+}
+
+// sortScan is a sort running in the background, closing cancel stops it.
+type sortScan struct {
+	seq        int
+	collection string
+	signature  string
+	cancel     chan struct{}
+}
+
+// sortedMsg carries the keys of a collection sorted in the background.
+type sortedMsg struct {
+	seq  int
+	keys [][]byte
+	err  error
+}
+
+var errSortCanceled = errors.New("sort cancelled")
+
+// sortSignature identifies what the rows of the active collection are sorted by.
+func (m Model) sortSignature() string {
+	var b strings.Builder
+	b.WriteString(describeSort(m.activeSort()))
+	if q := m.activeQuery(); q != nil {
+		b.WriteString("\x00" + q.Source)
+	}
+	for _, col := range m.sortColumns() {
+		b.WriteString("\x00" + strings.Join(col, "\x01"))
+	}
+	return b.String()
+}
+
+// pruneSort drops the sort keys of columns the table doesn't show anymore and returns their names.
+func (m *Model) pruneSort() []string {
+	if len(m.collections) == 0 {
+		return nil
+	}
+	collection := m.collections[m.activeCollection]
+	var keys []sortKey
+	var dropped []string
+	for _, k := range m.sorts[collection] {
+		shown := false
+		for _, col := range m.columns {
+			if col[0] == k.column {
+				shown = true
+				break
+			}
+		}
+		if shown {
+			keys = append(keys, k)
+		} else {
+			dropped = append(dropped, k.column)
+		}
+	}
+	if len(keys) == 0 {
+		delete(m.sorts, collection)
+	} else {
+		m.sorts[collection] = keys
+	}
+	return dropped
+}
+
+// sortColumns returns the aliases of the columns of the sort keys, in the order of the keys.
+func (m Model) sortColumns() [][]string {
+	var columns [][]string
+	for _, k := range m.activeSort() {
+		for _, col := range m.columns {
+			if col[0] == k.column {
+				columns = append(columns, col)
+				break
+			}
+		}
+	}
+	return columns
+}
+
+// sortedKeys returns the keys of the active collection in sorted order, nil when it isn't sorted or
+// its first sort is still running. The order of an earlier sort is kept while it is sorted again.
+func (m Model) sortedKeys() [][]byte {
+	if m.driver == nil || len(m.collections) == 0 || len(m.activeSort()) == 0 {
+		return nil
+	}
+	s := m.sorted[m.collections[m.activeCollection]]
+	if s == nil || s.signature != m.sortSignature() {
+		return nil
+	}
+	return s.keys
+}
+
+// ensureSort sorts the active collection in the background unless its sorted order is up to date
+// or already being worked out.
+func (m *Model) ensureSort() tea.Cmd {
+	keys := m.activeSort()
+	if m.driver == nil || len(keys) == 0 {
+		m.stopSort()
+		return nil
+	}
+	collection, signature := m.collections[m.activeCollection], m.sortSignature()
+	if s := m.sorted[collection]; s != nil && s.signature == signature && !s.stale {
+		return nil
+	}
+	if scan := m.sortScan; scan != nil && scan.collection == collection && scan.signature == signature {
+		return nil
+	}
+	m.stopSort()
+
+	m.sortSeq++
+	scan := &sortScan{seq: m.sortSeq, collection: collection, signature: signature, cancel: make(chan struct{})}
+	m.sortScan = scan
+	db, filter, columns := m.driver, m.rowFilter(), m.sortColumns()
+	var desc []bool
+	for _, k := range keys {
+		desc = append(desc, k.desc)
+	}
+	return func() tea.Msg {
+		keys, err := sortCollection(db, collection, filter, columns, desc, scan.cancel)
+		return sortedMsg{seq: scan.seq, keys: keys, err: err}
+	}
+}
+
+// stopSort cancels the sort running in the background.
+func (m *Model) stopSort() {
+	if m.sortScan != nil {
+		close(m.sortScan.cancel)
+		m.sortScan = nil
+	}
+}
+
+// resort sorts the active collection again after its documents changed, the rows stay in the order
+// of the last sort until it is done.
+func (m *Model) resort() tea.Cmd {
+	m.staleSorts()
+	return m.ensureSort()
+}
+
+// staleSorts marks every sorted order as out of date, collections are sorted again when they are shown.
+func (m *Model) staleSorts() {
+	for _, s := range m.sorted {
+		s.stale = true
+	}
+	m.stopSort()
+}
+
+// sortCollection returns the keys of the documents matching filter in the order of the columns,
+// only the values of the columns are kept while the collection is read.
+func sortCollection(db *store.DB, collection string, filter func(doc kmap) bool, columns [][]string, desc []bool, cancel chan struct{}) ([][]byte, error) {
+	type entry struct {
+		key    []byte
+		values []any
+	}
+	var entries []entry
+	err := store.Find(db, collection, bingo.Query[kmap]{Filter: filter}, func(key []byte, doc *kmap) error {
+		select {
+		case <-cancel:
+			return errSortCanceled
+		default:
+		}
+		values := make([]any, len(columns))
+		for i, col := range columns {
+			values[i], _ = columnValue(key, *doc, col)
+		}
+		entries = append(entries, entry{key: append([]byte{}, key...), values: values})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		for i := range columns {
+			if c := compareValues(entries[a].values[i], entries[b].values[i], desc[i]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	keys := make([][]byte, len(entries))
+	for i, e := range entries {
+		keys[i] = e.key
+	}
+	return keys, nil
+}
+
+// sortDone keeps the sorted order and loads the rows again in it, around the row of a pending jump
+// or the record under the cursor.
+func (m Model) sortDone(msg sortedMsg) (tea.Model, tea.Cmd) {
+	scan := m.sortScan
+	if scan == nil || msg.seq != scan.seq {
+		return m, nil
+	}
+	m.sortScan = nil
+	if msg.err != nil {
+		m.Error(fmt.Sprintf("Sorting failed: %v", msg.err))
+		return m, nil
+	}
+	m.sorted[scan.collection] = &sortedRows{signature: scan.signature, keys: msg.keys}
+	if len(m.collections) == 0 || m.collections[m.activeCollection] != scan.collection {
+		return m, nil
+	}
+
+	current, _ := m.cursorKey()
+	if m.jump != nil {
+		current = m.jump.key
+	}
+	m.Info(fmt.Sprintf("Sorted by %v", describeSort(m.activeSort())))
+	return m, tea.Batch(m.reloadRows(current), m.ClearInfoAfter("3s"))
+}
+
+// forgetSorted takes deleted rows out of the sorted order of the active collection.
+func (m *Model) forgetSorted(deleted map[string]bool) {
+	s := m.sorted[m.collections[m.activeCollection]]
+	if s == nil {
+		return
+	}
+	keys := make([][]byte, 0, len(s.keys))
+	for _, k := range s.keys {
+		if !deleted[string(k)] {
+			keys = append(keys, k)
+		}
+	}
+	s.keys = keys
+}
+
+// rebuildTable fills a new table with the loaded rows, the table can't replace rows in place.
+// The cursor stays on the record and column it was on.
+func (m *Model) rebuildTable() {
+	current, _ := m.cursorKey()
+	m.rebuildTableAt(current)
+}
+
+// rebuildTableAt fills a new table with the loaded rows and puts the cursor on the record stored under key.
+func (m *Model) rebuildTableAt(current []byte) {
+	x, _ := m.table.GetCursorLocation()
+
+	m.resetTable()
+	var err error
+	m.table, err = m.table.AddRows(m.displayRows())
+	if err != nil {
+		m.Error(fmt.Sprintf("Failed to render table: %v", err))
+	}
+	for i := 0; i < x; i++ {
+		m.table.CursorRight()
+	}
+	for i, k := range m.rowKeys {
+		if bytes.Equal(k, current) {
+			m.setCursor(i)
+			break
+		}
+	}
+}
+
+// setCursor moves the table cursor up or down to row y, the table has no way to set it directly.
+func (m *Model) setCursor(y int) {
+	_, current := m.table.GetCursorLocation()
+	for ; current < y; current++ {
+		m.table.CursorDown()
+	}
+	for ; current > y; current-- {
+		m.table.CursorUp()
+	}
+}
+
+// resetTable replaces the table with an empty one, headers carry the sort indicators.
+func (m *Model) resetTable() {
+	m.table = stick.NewTable(0, 0, m.tableHeaders())
+	m.table.SetStyles(map[stick.TableStyleKey]lipgloss.Style{
+		stick.TableHeaderStyleKey: accentStyle,
+		stick.TableFooterStyleKey: lipgloss.NewStyle(),
+	})
+}
+
+// sortIndicator returns the header suffix for a column, the priority is only shown with several keys.
+func (m Model) sortIndicator(column string) string {
+	keys := m.activeSort()
+	for i, k := range keys {
+		if k.column != column {
+			continue
+		}
+		arrow := "▲"
+		if k.desc {
+			arrow = "▼"
+		}
+		if len(keys) > 1 {
+			return fmt.Sprintf(" %v%v", arrow, i+1)
+		}
+		return " " + arrow
+	}
+	return ""
+}
+
+// compareValues orders decoded values by type: numbers numerically, times chronologically, other
+// strings lexically and bools false first. Missing values always come last, whatever the direction.
+func compareValues(a, b any, desc bool) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return 1
+		}
+		return -1
+	}
+
+	c := compareKinds(a, b)
+	if desc {
+		return -c
+	}
+	return c
+}
+
+func compareKinds(a, b any) int {
+	ra, rb := kindRank(a), kindRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+
+	switch av := a.(type) {
+	case float64:
+		return compareOrdered(av, b.(float64))
+	case bool:
+		bv := b.(bool)
+		switch {
+		case av == bv:
+			return 0
+		case !av:
+			return -1
+		}
+		return 1
+	case string:
+		bv := b.(string)
+		if ta, ok := parseTime(av); ok {
+			if tb, ok := parseTime(bv); ok {
+				return ta.Compare(tb)
+			}
+		}
+		if c := strings.Compare(strings.ToLower(av), strings.ToLower(bv)); c != 0 {
+			return c
+		}
+		return strings.Compare(av, bv)
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// kindRank keeps values of different types apart, numbers first and nested values last.
+func kindRank(v any) int {
+	switch v.(type) {
+	case float64:
+		return 0
+	case bool:
+		return 1
+	case string:
+		return 2
+	}
+	return 3
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, bool) {
+	if len(s) < len("2006-01-02") || s[4] != '-' {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bingoviewer/picker"
+	"bingoviewer/store"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// STATS_LARGEST is how many of the largest documents the statistics list per collection.
+const STATS_LARGEST = 5
+
+// statsDoneMsg carries the statistics collected in the background.
+type statsDoneMsg struct {
+	seq   int
+	stats store.Stats
+	err   error
+}
+
+// statsPanel shows the statistics of the open database.
+type statsPanel struct {
+	seq      int
+	running  bool
+	stats    *store.Stats
+	err      error
+	viewport viewport.Model
+}
+
+// collectStats walks every collection of the database for the sizes of its documents.
+func collectStats(db *store.DB, collections []string, largest int, seq int) tea.Cmd {
+	return func() tea.Msg {
+		stats, err := db.Stats(collections, largest)
+		return statsDoneMsg{seq: seq, stats: stats, err: err}
+	}
+}
+
+func (m Model) showStats() (tea.Model, tea.Cmd) {
+	if m.driver == nil {
+		return m, nil
+	}
+	m.statsView = statsPanel{
+		seq:      m.statsView.seq + 1,
+		running:  true,
+		viewport: viewport.New(m.window.width-4, m.window.height-11),
+	}
+	m.state = StatsPanel
+	return m, collectStats(m.driver, append([]string{}, m.collections...), STATS_LARGEST, m.statsView.seq)
+}
+
+func (m Model) statsDone(msg statsDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.statsView.seq {
+		return m, nil
+	}
+	m.statsView.running = false
+	m.statsView.err = msg.err
+	m.statsView.stats = &msg.stats
+	m.statsView.viewport.SetContent(m.renderStats())
+	return m, nil
+}
+
+func (m Model) updateStatsPanel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	panel := &m.statsView
+	switch {
+	case key.Matches(msg, m.keys.Escape, m.keys.Stats):
+		m.state = Normal
+		// statistics still being collected are dropped
+		panel.seq++
+		return m, nil
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case msg.String() == "r":
+		if panel.running {
+			return m, nil
+		}
+		return m.showStats()
+	}
+	var cmd tea.Cmd
+	panel.viewport, cmd = panel.viewport.Update(msg)
+	return m, cmd
+}
+
+func (m Model) RenderStatsPanel() string {
+	panel := m.statsView
+	title := fmt.Sprintf("Statistics of %v", logoStyle.Copy().PaddingLeft(0).Render(m.DatabaseFile))
+	switch {
+	case panel.running:
+		return fmt.Sprintf("%v\n\nReading every collection...", title)
+	case panel.err != nil:
+		return fmt.Sprintf("%v\n\n%v", title, errorStyle.Render(panel.err.Error()))
+	}
+	// laid out again for the current window size, the scroll position is kept
+	panel.viewport.Width = m.window.width - 4
+	panel.viewport.Height = m.window.height - 11
+	panel.viewport.SetContent(m.renderStats())
+	return fmt.Sprintf("%v\n\n%v\n\n[r] refresh    [esc] close", title, panel.viewport.View())
+}
+
+func (m Model) renderStats() string {
+	var b strings.Builder
+	// a strings.Builder never fails to write
+	_ = writeStats(&b, m.statsView.stats, func(s string) string {
+		return logoStyle.Copy().PaddingLeft(0).Render(s)
+	})
+	return b.String()
+}
+
+// writeStats writes the statistics as aligned tables, heading styles the title of each table.
+// It returns the first error writing to w.
+func writeStats(w io.Writer, s *store.Stats, heading func(string) string) error {
+	var err error
+	keep := func(_ int, e error) {
+		if err == nil {
+			err = e
+		}
+	}
+	table := func(title string, header []string, rows [][]string) {
+		keep(fmt.Fprintf(w, "%v\n", heading(title)))
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		if header != nil {
+			keep(fmt.Fprintln(tw, strings.Join(header, "\t")))
+		}
+		for _, row := range rows {
+			keep(fmt.Fprintln(tw, strings.Join(row, "\t")))
+		}
+		keep(0, tw.Flush())
+	}
+
+	table("File", nil, [][]string{
+		{"path", s.File},
+		{"size", picker.FormatSize(s.FileSize)},
+		{"pages", fmt.Sprintf("%v of %v", s.Pages, picker.FormatSize(int64(s.PageSize)))},
+		{"free pages", fmt.Sprintf("%v free, %v pending, %.1f%% fragmented", s.FreePages, s.PendingPages, s.Fragmentation*100)},
+		{"free space", picker.FormatSize(s.FreeBytes)},
+		{"transaction", fmt.Sprint(s.TxID)},
+	})
+
+	var collections, largest, keys [][]string
+	for _, c := range s.Collections {
+		fill := "-"
+		if c.AllocatedBytes > 0 {
+			fill = fmt.Sprintf("%.0f%%", float64(c.InuseBytes)*100/float64(c.AllocatedBytes))
+		}
+		collections = append(collections, []string{c.Name, fmt.Sprint(c.Documents), picker.FormatSize(c.TotalBytes),
+			picker.FormatSize(int64(c.AverageBytes)), fmt.Sprint(c.LeafPages + c.BranchPages + c.OverflowPages), fill})
+		for _, d := range c.Largest {
+			largest = append(largest, []string{c.Name, rawName([]byte(d.Key)), picker.FormatSize(int64(d.Bytes))})
+		}
+		for _, k := range c.KeyLengths {
+			length := fmt.Sprint(k.Min)
+			if k.Max != k.Min {
+				length = fmt.Sprintf("%v-%v", k.Min, k.Max)
+			}
+			keys = append(keys, []string{c.Name, length, fmt.Sprint(k.Count)})
+		}
+	}
+	keep(fmt.Fprintln(w))
+	table("Collections", []string{"COLLECTION", "DOCUMENTS", "TOTAL", "AVERAGE", "PAGES", "FILL"}, collections)
+	if len(largest) > 0 {
+		keep(fmt.Fprintln(w))
+		table("Largest documents", []string{"COLLECTION", "KEY", "SIZE"}, largest)
+	}
+	if len(keys) > 0 {
+		keep(fmt.Fprintln(w))
+		table("Key lengths", []string{"COLLECTION", "BYTES", "KEYS"}, keys)
+	}
+	return err
+}
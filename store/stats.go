@@ -0,0 +1,155 @@
+package store
+
+import (
+	"go.etcd.io/bbolt"
+	"os"
+	"sort"
+)
+
+// Stats is how the database file is laid out and how much of it each collection takes.
+type Stats struct {
+	File     string `json:"file"`
+	FileSize int64  `json:"file_size"`
+	PageSize int    `json:"page_size"`
+	// Pages is the number of pages in use up to the end of the data, the file may be larger.
+	Pages        int   `json:"pages"`
+	FreePages    int   `json:"free_pages"`
+	PendingPages int   `json:"pending_pages"`
+	FreeBytes    int64 `json:"free_bytes"`
+	// Fragmentation is the part of the pages that is free and waiting to be reused.
+	Fragmentation float64           `json:"fragmentation"`
+	TxID          int               `json:"txid"`
+	Collections   []CollectionStats `json:"collections"`
+}
+
+// CollectionStats is the size of a collection, values and keys as they are stored.
+type CollectionStats struct {
+	Name         string  `json:"name"`
+	Documents    int     `json:"documents"`
+	TotalBytes   int64   `json:"total_bytes"`
+	AverageBytes float64 `json:"average_bytes"`
+	// AllocatedBytes is what the pages of the collection take, InuseBytes how much of them holds data.
+	AllocatedBytes int64          `json:"allocated_bytes"`
+	InuseBytes     int64          `json:"inuse_bytes"`
+	LeafPages      int            `json:"leaf_pages"`
+	BranchPages    int            `json:"branch_pages"`
+	OverflowPages  int            `json:"overflow_pages"`
+	Largest        []DocumentSize `json:"largest"`
+	KeyLengths     []KeyLengths   `json:"key_lengths"`
+}
+
+// DocumentSize is the stored size of a document.
+type DocumentSize struct {
+	Key   string `json:"key"`
+	Bytes int    `json:"bytes"`
+}
+
+// KeyLengths counts the keys from Min to Max bytes long.
+type KeyLengths struct {
+	Min   int `json:"min"`
+	Max   int `json:"max"`
+	Count int `json:"count"`
+}
+
+// maxKeyLengths is how many distinct key lengths are listed one by one before they are grouped in powers of two.
+const maxKeyLengths = 12
+
+// Stats reads the bbolt statistics of the file and walks every collection for the size of its documents,
+// largest is how many of the biggest documents are kept per collection.
+func (d *DB) Stats(collections []string, largest int) (Stats, error) {
+	s := Stats{File: d.Path, PageSize: d.db.Info().PageSize}
+	if d.IsSnapshot() {
+		s.File = d.Source
+	}
+	if info, err := os.Stat(d.Path); err == nil {
+		s.FileSize = info.Size()
+	}
+	dbStats := d.db.Stats()
+	s.FreePages, s.PendingPages, s.FreeBytes = dbStats.FreePageN, dbStats.PendingPageN, int64(dbStats.FreeAlloc)
+
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		s.TxID = tx.ID()
+		s.Pages = int(tx.Size() / int64(s.PageSize))
+		for _, name := range collections {
+			bucket, err := collectionBucket(tx, name)
+			if err != nil {
+				return err
+			}
+			s.Collections = append(s.Collections, collectionStats(name, bucket, largest))
+		}
+		return nil
+	})
+	if s.Pages > 0 {
+		s.Fragmentation = float64(s.FreePages+s.PendingPages) / float64(s.Pages)
+	}
+	return s, err
+}
+
+// collectionStats walks the bucket of a collection, a nil bucket is a collection without documents.
+func collectionStats(name string, bucket *bbolt.Bucket, largest int) CollectionStats {
+	c := CollectionStats{Name: name}
+	if bucket == nil {
+		return c
+	}
+	b := bucket.Stats()
+	c.AllocatedBytes = int64(b.BranchAlloc + b.LeafAlloc)
+	c.InuseBytes = int64(b.BranchInuse + b.LeafInuse)
+	c.LeafPages, c.BranchPages, c.OverflowPages = b.LeafPageN, b.BranchPageN, b.LeafOverflowN+b.BranchOverflowN
+
+	lengths := map[int]int{}
+	_ = bucket.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil
+		}
+		c.Documents++
+		c.TotalBytes += int64(len(v))
+		lengths[len(k)]++
+		// the largest documents are kept sorted, biggest first
+		if largest > 0 && (len(c.Largest) < largest || len(v) > c.Largest[len(c.Largest)-1].Bytes) {
+			i := sort.Search(len(c.Largest), func(i int) bool { return c.Largest[i].Bytes < len(v) })
+			c.Largest = append(c.Largest, DocumentSize{})
+			copy(c.Largest[i+1:], c.Largest[i:])
+			c.Largest[i] = DocumentSize{Key: string(k), Bytes: len(v)}
+			if len(c.Largest) > largest {
+				c.Largest = c.Largest[:largest]
+			}
+		}
+		return nil
+	})
+	if c.Documents > 0 {
+		c.AverageBytes = float64(c.TotalBytes) / float64(c.Documents)
+	}
+	c.KeyLengths = groupKeyLengths(lengths)
+	return c
+}
+
+// groupKeyLengths lists how many keys have each length, or each range of lengths up to the next power
+// of two when there are too many distinct lengths to list.
+func groupKeyLengths(lengths map[int]int) []KeyLengths {
+	sizes := make([]int, 0, len(lengths))
+	for size := range lengths {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	var groups []KeyLengths
+	for _, size := range sizes {
+		low, high := size, size
+		if len(sizes) > maxKeyLengths {
+			high = 1
+			for high < size {
+				high *= 2
+			}
+			low = high/2 + 1
+			if high == 1 {
+				low = 0
+			}
+		}
+		if n := len(groups); n > 0 && groups[n-1].Min == low {
+			groups[n-1].Count += lengths[size]
+			continue
+		}
+		groups = append(groups, KeyLengths{Min: low, Max: high, Count: lengths[size]})
+	}
+	return groups
+}
@@ -0,0 +1,1068 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/nokusukun/bingo"
+	"go.etcd.io/bbolt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const collectionPrefix = "collection:"
+
+// ErrLocked is returned when another process holds a lock on the database file that conflicts with ours.
+var ErrLocked = errors.New("database is locked by another process")
+
+// ErrCanceled is returned when opening was cancelled while waiting for the file lock.
+var ErrCanceled = errors.New("open cancelled")
+
+// ErrInconsistentSnapshot is returned when the database kept changing while it was being copied.
+var ErrInconsistentSnapshot = errors.New("database kept changing while it was copied")
+
+// ErrReadOnly is returned when writing to a database that was opened read-only.
+var ErrReadOnly = errors.New("database is open read-only")
+
+// Options configures how a database file is opened.
+type Options struct {
+	// ReadOnly takes a shared lock on the file, other readers can still open it.
+	ReadOnly bool
+	// Timeout is how long to wait for the file lock, zero waits until Cancel is closed.
+	Timeout time.Duration
+	// Cancel stops waiting for the file lock when it is closed.
+	Cancel <-chan struct{}
+}
+
+// lockPoll is how long each attempt at taking the file lock waits, Open checks Cancel between attempts.
+const lockPoll = 100 * time.Millisecond
+
+// snapshotAttempts is how often copying a database that changes while it is copied is tried.
+const snapshotAttempts = 5
+
+// DB reads bingo collections straight from the bbolt file so the lock mode can be chosen,
+// bingo.NewDriver always opens the file for writing.
+type DB struct {
+	// Path is the file that is actually opened.
+	Path string
+	// Source is the file Path was copied from when the database is a snapshot.
+	Source   string
+	ReadOnly bool
+
+	db *bbolt.DB
+}
+
+// Open opens the database file, ErrLocked is returned if the lock couldn't be taken before the timeout
+// and ErrCanceled if Cancel was closed first.
+func Open(path string, opts Options) (*DB, error) {
+	var deadline time.Time
+	if opts.Timeout > 0 {
+		deadline = time.Now().Add(opts.Timeout)
+	}
+	for {
+		// bbolt can't be interrupted while it waits for the lock, so it only waits a little at a time
+		wait := lockPoll
+		if !deadline.IsZero() {
+			if wait = min(wait, time.Until(deadline)); wait <= 0 {
+				return nil, ErrLocked
+			}
+		}
+		db, err := bbolt.Open(path, 0600, &bbolt.Options{
+			ReadOnly: opts.ReadOnly,
+			Timeout:  wait,
+			// read-only databases skip the freelist otherwise, Stats needs it for the free pages
+			PreLoadFreelist: true,
+		})
+		if errors.Is(err, bbolt.ErrTimeout) {
+			select {
+			case <-opts.Cancel:
+				return nil, ErrCanceled
+			default:
+				continue
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &DB{
+			Path:     path,
+			ReadOnly: opts.ReadOnly,
+			db:       db,
+		}, nil
+	}
+}
+
+// OpenSnapshot copies the file to the temp directory and opens the copy read-only, the copy is removed
+// again when the database is closed.
+//
+// The snapshot is best-effort: the file is copied without a lock while its owner may be writing to it.
+// A copy is only kept when both meta pages of the file are the same before and after copying and the
+// copy's meta pages match them, otherwise copying is tried again. That catches commits that happen during
+// the copy, but not a writer that ignores bbolt's copy-on-write layout.
+func OpenSnapshot(path string, opts Options) (*DB, error) {
+	var snapshot string
+	for attempt := 0; ; attempt++ {
+		var err error
+		snapshot, err = copyConsistent(path)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrInconsistentSnapshot) || attempt == snapshotAttempts-1 {
+			return nil, fmt.Errorf("snapshot failed: %w", err)
+		}
+		select {
+		case <-opts.Cancel:
+			return nil, ErrCanceled
+		case <-time.After(lockPoll):
+		}
+	}
+	opts.ReadOnly = true
+	d, err := Open(snapshot, opts)
+	if err != nil {
+		_ = os.Remove(snapshot)
+		return nil, err
+	}
+	d.Source = path
+	return d, nil
+}
+
+// copyConsistent copies the file to the temp directory and checks that no commit happened while it was copied.
+func copyConsistent(path string) (string, error) {
+	before, err := readMetas(path)
+	if err != nil {
+		return "", err
+	}
+	snapshot, err := copyToTemp(path)
+	if err != nil {
+		return "", err
+	}
+	after, err := readMetas(path)
+	if err == nil && after != before {
+		err = ErrInconsistentSnapshot
+	}
+	if err == nil {
+		var copied [2]metaPage
+		if copied, err = readMetas(snapshot); err == nil && copied != before {
+			err = ErrInconsistentSnapshot
+		}
+	}
+	if err != nil {
+		_ = os.Remove(snapshot)
+		return "", err
+	}
+	return snapshot, nil
+}
+
+func copyToTemp(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	dst, err := os.CreateTemp("", fmt.Sprintf("bingoviewer-%v-*.db", name))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		os.Remove(dst.Name())
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
+
+// metaPage is what a snapshot compares of the two meta pages at the start of a bbolt file.
+type metaPage struct {
+	txid  uint64
+	valid bool
+}
+
+// bbolt's page header is 16 bytes, the meta that follows it ends in a checksum over the 56 bytes before it
+const (
+	pageHeaderSize = 16
+	metaChecksumAt = 56
+	boltMagic      = 0xED0CDAED
+)
+
+// readMetas reads both meta pages of a bbolt file, a meta page that is torn or damaged fails its checksum.
+func readMetas(path string) ([2]metaPage, error) {
+	var metas [2]metaPage
+	f, err := os.Open(path)
+	if err != nil {
+		return metas, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, pageHeaderSize+metaChecksumAt+8)
+	pageSize := int64(os.Getpagesize())
+	bolt := false
+	for i := range metas {
+		if _, err := f.ReadAt(buf, int64(i)*pageSize); err != nil {
+			if errors.Is(err, io.EOF) {
+				return metas, bbolt.ErrInvalid
+			}
+			return metas, err
+		}
+		meta := buf[pageHeaderSize:]
+		magic := binary.NativeEndian.Uint32(meta) == boltMagic
+		bolt = bolt || magic
+		h := fnv.New64a()
+		_, _ = h.Write(meta[:metaChecksumAt])
+		metas[i] = metaPage{
+			txid:  binary.NativeEndian.Uint64(meta[metaChecksumAt-8:]),
+			valid: magic && binary.NativeEndian.Uint64(meta[metaChecksumAt:]) == h.Sum64(),
+		}
+		if i == 0 && metas[0].valid {
+			// the second meta page comes after the first page, whatever size the file was created with
+			pageSize = int64(binary.NativeEndian.Uint32(meta[8:]))
+		}
+	}
+	switch {
+	case !bolt:
+		return metas, bbolt.ErrInvalid
+	case !metas[0].valid && !metas[1].valid:
+		return metas, ErrInconsistentSnapshot
+	}
+	return metas, nil
+}
+
+// IsSnapshot returns true if the database is a copy of another file.
+func (d *DB) IsSnapshot() bool {
+	return d.Source != ""
+}
+
+// Close closes the database file and removes it if it was a snapshot.
+func (d *DB) Close() error {
+	err := d.db.Close()
+	if d.IsSnapshot() {
+		if rmErr := os.Remove(d.Path); err == nil {
+			err = rmErr
+		}
+	}
+	return err
+}
+
+// ReadMetadata returns the value bingo stored under the metadata key k.
+func (d *DB) ReadMetadata(k string) (any, error) {
+	var metadata bingo.Metadata
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(bingo.METADATA_COLLECTION_NAME))
+		if bucket == nil {
+			return bingo.ErrDocumentNotFound
+		}
+		v := bucket.Get([]byte(k))
+		if v == nil {
+			return bingo.ErrDocumentNotFound
+		}
+		return bingo.Unmarshaller.Unmarshal(v, &metadata)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return metadata.V, nil
+}
+
+// GetCollections lists the collections registered in bingo's metadata, in the same order bingo returns them.
+func (d *DB) GetCollections() ([]string, error) {
+	var collections []string
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(bingo.METADATA_COLLECTION_NAME))
+		if bucket == nil {
+			return nil
+		}
+		wbucket := &bingo.WrappedBucket{Bucket: bucket}
+		return wbucket.ReverseIter(func(k, v []byte) error {
+			if !strings.HasPrefix(string(k), collectionPrefix) {
+				return nil
+			}
+			var metadata bingo.Metadata
+			if err := bingo.Unmarshaller.Unmarshal(v, &metadata); err != nil {
+				return err
+			}
+			if active, ok := metadata.V.(bool); ok && active {
+				collections = append(collections, strings.TrimPrefix(metadata.K, collectionPrefix))
+			}
+			return nil
+		})
+	})
+	return collections, err
+}
+
+// FieldsOf returns the field names and aliases bingo recorded for the collection.
+func (d *DB) FieldsOf(name string) ([][]string, error) {
+	r, err := d.ReadMetadata(bingo.FIELDS_COLLECTION_NAME + name)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := r.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unknown field structure: %v", r)
+	}
+	var result [][]string
+	for _, i := range v {
+		s, ok := i.(string)
+		if !ok {
+			return nil, fmt.Errorf("unknown inner field structure: %v", i)
+		}
+		result = append(result, strings.Split(s, bingo.FIELD_ALIAS_SEPARATOR))
+	}
+	return result, nil
+}
+
+// collectionBucket returns the bucket of a collection. bingo registers a collection before its first
+// document creates the bucket, so a registered collection without one is empty and the bucket is nil.
+func collectionBucket(tx *bbolt.Tx, collection string) (*bbolt.Bucket, error) {
+	if bucket := tx.Bucket([]byte(collection)); bucket != nil {
+		return bucket, nil
+	}
+	if meta := tx.Bucket([]byte(bingo.METADATA_COLLECTION_NAME)); meta != nil {
+		if v := meta.Get([]byte(collectionPrefix + collection)); v != nil {
+			var metadata bingo.Metadata
+			if err := bingo.Unmarshaller.Unmarshal(v, &metadata); err != nil {
+				return nil, err
+			}
+			if active, ok := metadata.V.(bool); ok && active {
+				return nil, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("bucket %s not found", collection)
+}
+
+// Find walks the collection newest first like bingo's queries and calls fn for every document matching q.
+// The key passed to fn is only valid until fn returns.
+func Find[T bingo.DocumentSpec](d *DB, collection string, q bingo.Query[T], fn func(key []byte, doc *T) error) error {
+	found := 0
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		bucket, err := collectionBucket(tx, collection)
+		if bucket == nil {
+			return err
+		}
+
+		keys := q.Keys
+		for _, k := range q.KeysStr {
+			keys = append(keys, []byte(k))
+		}
+		if len(keys) > 0 {
+			for _, k := range keys {
+				v := bucket.Get(k)
+				if v == nil {
+					continue
+				}
+				var document T
+				if err := bingo.Unmarshaller.Unmarshal(v, &document); err != nil {
+					return err
+				}
+				if err := fn(k, &document); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		seen := 0
+		wbucket := &bingo.WrappedBucket{Bucket: bucket}
+		return wbucket.ReverseIter(func(k, v []byte) error {
+			seen++
+			if seen <= q.Skip {
+				return nil
+			}
+			var document T
+			if err := bingo.Unmarshaller.Unmarshal(v, &document); err != nil {
+				return err
+			}
+			if q.Filter != nil && !q.Filter(document) {
+				return nil
+			}
+			found++
+			if err := fn(k, &document); err != nil {
+				return err
+			}
+			if q.Count > 0 && found >= q.Count {
+				return errStop
+			}
+			return nil
+		})
+	})
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+var errStop = errors.New("stop")
+
+// Page walks the collection newest first starting below the key before, nil starts from the newest document.
+// The first q.Skip documents matching q.Filter are passed over, without decoding them when there is no filter.
+// At most q.Count documents matching q.Filter are passed to fn, the returned key is where the next page
+// starts and is nil once the collection is exhausted.
+func Page[T bingo.DocumentSpec](d *DB, collection string, q bingo.Query[T], before []byte, fn func(key []byte, doc *T) error) ([]byte, error) {
+	var next []byte
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		bucket, err := collectionBucket(tx, collection)
+		if bucket == nil {
+			return err
+		}
+
+		c := bucket.Cursor()
+		var k, v []byte
+		if before == nil {
+			k, v = c.Last()
+		} else if k, _ = c.Seek(before); k == nil {
+			k, v = c.Last()
+		} else {
+			// Seek lands on the first key >= before, the one in front of it is where we continue
+			k, v = c.Prev()
+		}
+
+		found, skipped := 0, 0
+		for ; k != nil; k, v = c.Prev() {
+			if v == nil {
+				// nested bucket, bingo never creates these inside a collection
+				continue
+			}
+			if q.Filter == nil && skipped < q.Skip {
+				skipped++
+				continue
+			}
+			var document T
+			if err := bingo.Unmarshaller.Unmarshal(v, &document); err != nil {
+				return err
+			}
+			if q.Filter != nil && !q.Filter(document) {
+				continue
+			}
+			if skipped < q.Skip {
+				skipped++
+				continue
+			}
+			found++
+			if err := fn(k, &document); err != nil {
+				return err
+			}
+			if q.Count > 0 && found >= q.Count {
+				next = append([]byte{}, k...)
+				return nil
+			}
+		}
+		return nil
+	})
+	return next, err
+}
+
+// PageAbove is the page in front of the one Page would start below the key after: it walks the collection
+// oldest first starting above after, nil starts from the oldest document. At most q.Count documents matching
+// q.Filter are passed to fn, newest first like Page passes them, more reports whether the walk stopped
+// before the newest document.
+func PageAbove[T bingo.DocumentSpec](d *DB, collection string, q bingo.Query[T], after []byte, fn func(key []byte, doc *T) error) (more bool, err error) {
+	type found struct {
+		key      []byte
+		document T
+	}
+	var page []found
+	err = d.db.View(func(tx *bbolt.Tx) error {
+		bucket, err := collectionBucket(tx, collection)
+		if bucket == nil {
+			return err
+		}
+
+		c := bucket.Cursor()
+		var k, v []byte
+		if after == nil {
+			k, v = c.First()
+		} else if k, v = c.Seek(after); k != nil && bytes.Equal(k, after) {
+			k, v = c.Next()
+		}
+		for ; k != nil; k, v = c.Next() {
+			if v == nil {
+				continue
+			}
+			if q.Count > 0 && len(page) >= q.Count {
+				more = true
+				return nil
+			}
+			var document T
+			if err := bingo.Unmarshaller.Unmarshal(v, &document); err != nil {
+				return err
+			}
+			if q.Filter != nil && !q.Filter(document) {
+				continue
+			}
+			page = append(page, found{key: append([]byte{}, k...), document: document})
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	for i := len(page) - 1; i >= 0; i-- {
+		if err := fn(page[i].key, &page[i].document); err != nil {
+			return false, err
+		}
+	}
+	return more, nil
+}
+
+// Count estimates the number of documents in the collection from the bucket statistics, without decoding them.
+func (d *DB) Count(collection string) (int, error) {
+	count := 0
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		bucket, err := collectionBucket(tx, collection)
+		if bucket == nil {
+			return err
+		}
+		count = bucket.Stats().KeyN
+		return nil
+	})
+	return count, err
+}
+
+// Fingerprints hashes every document of the collection by key, comparing two of them tells which documents
+// were added, changed or removed in between without keeping the documents around.
+func (d *DB) Fingerprints(collection string) (map[string]uint64, error) {
+	prints := map[string]uint64{}
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		bucket, err := collectionBucket(tx, collection)
+		if bucket == nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if v == nil {
+				return nil
+			}
+			h := fnv.New64a()
+			h.Write(v)
+			prints[string(k)] = h.Sum64()
+			return nil
+		})
+	})
+	return prints, err
+}
+
+// RawEntry is an entry of a bbolt bucket, a nested bucket or a key and the size of its value.
+type RawEntry struct {
+	Key    []byte
+	Bucket bool
+	Size   int
+}
+
+// rawBucket follows path down the bucket tree, bingo's own buckets and any others alike.
+func rawBucket(tx *bbolt.Tx, path [][]byte) (*bbolt.Bucket, error) {
+	var bucket *bbolt.Bucket
+	for i, name := range path {
+		if i == 0 {
+			bucket = tx.Bucket(name)
+		} else {
+			bucket = bucket.Bucket(name)
+		}
+		if bucket == nil {
+			return nil, fmt.Errorf("bucket %q not found", name)
+		}
+	}
+	return bucket, nil
+}
+
+// RawList lists the entries of the bucket at path in key order, the empty path lists the top level buckets.
+func (d *DB) RawList(path [][]byte) ([]RawEntry, error) {
+	var entries []RawEntry
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		if len(path) == 0 {
+			return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+				entries = append(entries, RawEntry{Key: append([]byte{}, name...), Bucket: true})
+				return nil
+			})
+		}
+		bucket, err := rawBucket(tx, path)
+		if err != nil {
+			return err
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			entries = append(entries, RawEntry{Key: append([]byte{}, k...), Bucket: v == nil, Size: len(v)})
+			return nil
+		})
+	})
+	return entries, err
+}
+
+// RawGet returns a copy of the value stored under key in the bucket at path.
+func (d *DB) RawGet(path [][]byte, key []byte) ([]byte, error) {
+	var value []byte
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		bucket, err := rawBucket(tx, path)
+		if err != nil {
+			return err
+		}
+		if bucket == nil {
+			return errors.New("top level entries are buckets")
+		}
+		v := bucket.Get(key)
+		if v == nil {
+			return fmt.Errorf("key %q not found", key)
+		}
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return value, err
+}
+
+// Get decodes the document stored under key.
+func Get[T bingo.DocumentSpec](d *DB, collection string, key []byte) (T, error) {
+	var document T
+	found := false
+	err := Find(d, collection, bingo.Query[T]{Keys: [][]byte{key}}, func(_ []byte, doc *T) error {
+		document = *doc
+		found = true
+		return nil
+	})
+	if err != nil {
+		return document, err
+	}
+	if !found {
+		return document, errors.Join(bingo.ErrDocumentNotFound, fmt.Errorf("document with id %v not found", string(key)))
+	}
+	return document, nil
+}
+
+// Update decodes the document stored under key, lets fn change it and writes it back in the same transaction.
+// The value is written to bbolt directly, bingo's validator and the collection's hooks don't run.
+func Update[T bingo.DocumentSpec](d *DB, collection string, key []byte, fn func(doc *T) error) error {
+	if d.ReadOnly {
+		return ErrReadOnly
+	}
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := collectionBucket(tx, collection)
+		if err != nil {
+			return err
+		}
+		if bucket == nil {
+			return errors.Join(bingo.ErrDocumentNotFound, fmt.Errorf("document with id %v not found", string(key)))
+		}
+		v := bucket.Get(key)
+		if v == nil {
+			return errors.Join(bingo.ErrDocumentNotFound, fmt.Errorf("document with id %v not found", string(key)))
+		}
+		var document T
+		if err := bingo.Unmarshaller.Unmarshal(v, &document); err != nil {
+			return err
+		}
+		if err := fn(&document); err != nil {
+			return err
+		}
+		data, err := bingo.Marshaller.Marshal(document)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(key, data)
+	})
+}
+
+// Delete removes the documents stored under keys in a single transaction,
+// if one of them is missing nothing is deleted.
+func (d *DB) Delete(collection string, keys [][]byte) error {
+	if d.ReadOnly {
+		return ErrReadOnly
+	}
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := collectionBucket(tx, collection)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if bucket == nil || bucket.Get(key) == nil {
+				return errors.Join(bingo.ErrDocumentNotFound, fmt.Errorf("document with id %v not found", string(key)))
+			}
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Insert stores the documents in a single transaction the way bingo's InsertMany does, a document
+// without a key gets the next sequence number of the collection. Existing keys fail with
+// bingo.ErrDocumentExists unless upsert is set. It returns the key every document was stored under.
+// Unlike InsertMany the values are written to bbolt directly, bingo's validator and the collection's
+// hooks don't run. Fields of the documents the collection didn't record yet are added to its fields.
+func (d *DB) Insert(collection string, keys [][]byte, docs []any, upsert bool) ([][]byte, error) {
+	if d.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	var ids [][]byte
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := collectionBucket(tx, collection)
+		if err != nil {
+			return err
+		}
+		if bucket == nil {
+			// the first document of a registered collection
+			if bucket, err = tx.CreateBucket([]byte(collection)); err != nil {
+				return err
+			}
+		}
+		for i, doc := range docs {
+			data, err := bingo.Marshaller.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			key := keys[i]
+			if len(key) == 0 {
+				seq, err := bucket.NextSequence()
+				if err != nil {
+					return err
+				}
+				key = []byte(fmt.Sprintf("%v", seq))
+			}
+			if !upsert && bucket.Get(key) != nil {
+				return fmt.Errorf("%w: %v", bingo.ErrDocumentExists, string(key))
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+			ids = append(ids, key)
+		}
+		return recordFields(tx, collection, fieldsOf(docs))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ImportResult counts what an import did, or would do for a dry run.
+type ImportResult struct {
+	Created  int
+	Updated  int
+	Rejected int
+	Removed  int
+}
+
+// fieldsOf lists the fields of the documents that are objects, in the order they are first seen.
+func fieldsOf(docs []any) []string {
+	var fields []string
+	seen := map[string]bool{}
+	for _, doc := range docs {
+		m, ok := doc.(map[string]any)
+		if !ok {
+			continue
+		}
+		names := make([]string, 0, len(m))
+		for name := range m {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		fields = append(fields, names...)
+	}
+	return fields
+}
+
+// recordFields adds the fields missing from the fields bingo recorded for the collection, a collection
+// without any gets them all. A field is known when it matches any alias of a recorded field.
+func recordFields(tx *bbolt.Tx, collection string, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	bucket, err := tx.CreateBucketIfNotExists([]byte(bingo.METADATA_COLLECTION_NAME))
+	if err != nil {
+		return err
+	}
+	k := bingo.FIELDS_COLLECTION_NAME + collection
+	var recorded []string
+	known := map[string]bool{}
+	if v := bucket.Get([]byte(k)); v != nil {
+		var metadata bingo.Metadata
+		if err := bingo.Unmarshaller.Unmarshal(v, &metadata); err != nil {
+			return err
+		}
+		list, ok := metadata.V.([]any)
+		if !ok {
+			return fmt.Errorf("unknown field structure: %v", metadata.V)
+		}
+		for _, f := range list {
+			s, ok := f.(string)
+			if !ok {
+				return fmt.Errorf("unknown inner field structure: %v", f)
+			}
+			recorded = append(recorded, s)
+			for _, alias := range strings.Split(s, bingo.FIELD_ALIAS_SEPARATOR) {
+				known[alias] = true
+			}
+		}
+	}
+	added := false
+	for _, f := range fields {
+		if !known[f] {
+			known[f] = true
+			recorded = append(recorded, f)
+			added = true
+		}
+	}
+	if !added {
+		return nil
+	}
+	data, err := bingo.Marshaller.Marshal(bingo.Metadata{K: k, V: recorded})
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(k), data)
+}
+
+// Importer writes documents into a collection in batches, every batch is one transaction.
+// A dry run only reads the collection to find out what would happen. Like Insert the documents are
+// written to bbolt directly, bingo's validator and the collection's hooks don't run.
+type Importer struct {
+	Collection string
+	Upsert     bool
+	DryRun     bool
+	Result     ImportResult
+
+	d *DB
+	// staging is the bucket a replacing import writes to until Commit swaps it in
+	staging []byte
+	// cleared is set once a dry run pretended to empty the collection
+	cleared bool
+	// seen holds the keys a dry run pretended to write
+	seen map[string]bool
+}
+
+// stagingPrefix starts the name of the bucket a replacing import is written to, it isn't a collection.
+const stagingPrefix = "bingoviewer-import:"
+
+// NewImporter prepares an import into collection, the bucket is created when it doesn't exist yet.
+func (d *DB) NewImporter(collection string, upsert, dryRun bool) (*Importer, error) {
+	if d.ReadOnly && !dryRun {
+		return nil, ErrReadOnly
+	}
+	return &Importer{
+		Collection: collection,
+		Upsert:     upsert,
+		DryRun:     dryRun,
+		d:          d,
+		seen:       map[string]bool{},
+	}, nil
+}
+
+// Replace makes the import replace every document of the collection. The batches are written to a
+// staging bucket and only Commit swaps them in, so the collection stays as it was when the import fails.
+func (i *Importer) Replace() error {
+	if i.DryRun {
+		count, err := i.d.Count(i.Collection)
+		if err != nil {
+			// a collection that doesn't exist yet has nothing to clear
+			count = 0
+		}
+		i.Result.Removed += count
+		i.cleared = true
+		return nil
+	}
+	staging := []byte(stagingPrefix + i.Collection)
+	err := i.d.db.Update(func(tx *bbolt.Tx) error {
+		// a staging bucket left behind by an import that was killed is dropped
+		if tx.Bucket(staging) != nil {
+			if err := tx.DeleteBucket(staging); err != nil {
+				return err
+			}
+		}
+		bucket, err := tx.CreateBucket(staging)
+		if err != nil {
+			return err
+		}
+		// documents without a key go on numbering where the collection got to
+		if old := tx.Bucket([]byte(i.Collection)); old != nil {
+			return bucket.SetSequence(old.Sequence())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	i.staging = staging
+	return nil
+}
+
+// Commit swaps the documents of a replacing import in for the documents of the collection, in one
+// transaction. Nested buckets of the collection are kept.
+func (i *Importer) Commit() error {
+	if i.DryRun || i.staging == nil {
+		return nil
+	}
+	err := i.d.db.Update(func(tx *bbolt.Tx) error {
+		staging := tx.Bucket(i.staging)
+		if staging == nil {
+			return fmt.Errorf("bucket %s not found", i.staging)
+		}
+		bucket, err := tx.CreateBucketIfNotExists([]byte(i.Collection))
+		if err != nil {
+			return err
+		}
+		var keys [][]byte
+		err = bucket.ForEach(func(k, v []byte) error {
+			if v != nil {
+				keys = append(keys, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		err = staging.ForEach(func(k, v []byte) error {
+			return bucket.Put(k, v)
+		})
+		if err != nil {
+			return err
+		}
+		if err := bucket.SetSequence(staging.Sequence()); err != nil {
+			return err
+		}
+		i.Result.Removed += len(keys)
+		return tx.DeleteBucket(i.staging)
+	})
+	if err != nil {
+		return err
+	}
+	i.staging = nil
+	return nil
+}
+
+// Abort drops the staging bucket of a replacing import that failed, the collection isn't touched.
+func (i *Importer) Abort() error {
+	if i.DryRun || i.staging == nil {
+		return nil
+	}
+	staging := i.staging
+	i.staging = nil
+	return i.d.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(staging) == nil {
+			return nil
+		}
+		return tx.DeleteBucket(staging)
+	})
+}
+
+// Reject counts a document that couldn't be imported.
+func (i *Importer) Reject() {
+	i.Result.Rejected++
+}
+
+// Write imports a batch, documents without a key get the next sequence number of the collection.
+// Documents whose key exists are rejected unless Upsert is set.
+func (i *Importer) Write(keys [][]byte, docs []any) error {
+	if i.DryRun {
+		return i.d.db.View(func(tx *bbolt.Tx) error {
+			bucket := tx.Bucket([]byte(i.Collection))
+			for n := range docs {
+				key := keys[n]
+				if len(key) == 0 {
+					i.Result.Created++
+					continue
+				}
+				exists := i.seen[string(key)] || (!i.cleared && bucket != nil && bucket.Get(key) != nil)
+				switch {
+				case !exists:
+					i.Result.Created++
+					i.seen[string(key)] = true
+				case i.Upsert:
+					i.Result.Updated++
+				default:
+					i.Result.Rejected++
+				}
+			}
+			return nil
+		})
+	}
+
+	name := []byte(i.Collection)
+	if i.staging != nil {
+		name = i.staging
+	}
+	var result ImportResult
+	err := i.d.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(name)
+		if err != nil {
+			return err
+		}
+		for n, doc := range docs {
+			key := keys[n]
+			if len(key) == 0 {
+				seq, err := bucket.NextSequence()
+				if err != nil {
+					return err
+				}
+				key = []byte(fmt.Sprintf("%v", seq))
+			}
+			exists := bucket.Get(key) != nil
+			if exists && !i.Upsert {
+				result.Rejected++
+				continue
+			}
+			data, err := bingo.Marshaller.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+			if exists {
+				result.Updated++
+			} else {
+				result.Created++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	i.Result.Created += result.Created
+	i.Result.Updated += result.Updated
+	i.Result.Rejected += result.Rejected
+	return nil
+}
+
+// RegisterCollection records a collection in bingo's metadata the way bingo.CollectionFrom does,
+// fields the collection didn't record yet are added to its fields.
+func (d *DB) RegisterCollection(name string, fields []string) error {
+	if d.ReadOnly {
+		return ErrReadOnly
+	}
+	return d.db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(bingo.METADATA_COLLECTION_NAME))
+		if err != nil {
+			return err
+		}
+		if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+			return err
+		}
+		write := func(k string, v any) error {
+			data, err := bingo.Marshaller.Marshal(bingo.Metadata{K: k, V: v})
+			if err != nil {
+				return err
+			}
+			return bucket.Put([]byte(k), data)
+		}
+		if err := recordFields(tx, name, fields); err != nil {
+			return err
+		}
+		return write(collectionPrefix+name, true)
+	})
+}
@@ -0,0 +1,290 @@
+package store
+
+import (
+	"errors"
+	"github.com/nokusukun/bingo"
+	"go.etcd.io/bbolt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newDatabase creates a bbolt file with a bucket holding one value.
+func newDatabase(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := bbolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte("people"))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte("ada"), []byte(`{"name":"Ada"}`))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadMetas(t *testing.T) {
+	path := newDatabase(t)
+	metas, err := readMetas(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !metas[0].valid || !metas[1].valid {
+		t.Fatalf("meta pages should be valid: %+v", metas)
+	}
+	if metas[0].txid == metas[1].txid {
+		t.Fatalf("meta pages should hold different transactions: %+v", metas)
+	}
+
+	// a meta page torn in the middle of a write fails its checksum
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte{0xff}, pageHeaderSize+40); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	torn, err := readMetas(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if torn[0].valid || !torn[1].valid {
+		t.Fatalf("only the first meta page should be invalid: %+v", torn)
+	}
+
+	other := filepath.Join(t.TempDir(), "other.txt")
+	if err := os.WriteFile(other, make([]byte, 64*1024), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readMetas(other); !errors.Is(err, bbolt.ErrInvalid) {
+		t.Fatalf("expected ErrInvalid for a file that isn't a database, got %v", err)
+	}
+}
+
+func TestOpenSnapshotOfLockedFile(t *testing.T) {
+	path := newDatabase(t)
+	writer, err := Open(path, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	if _, err := Open(path, Options{ReadOnly: true, Timeout: 200 * time.Millisecond}); !errors.Is(err, ErrLocked) {
+		t.Fatalf("expected ErrLocked, got %v", err)
+	}
+	snapshot, err := OpenSnapshot(path, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Source != path || !snapshot.ReadOnly {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	copied := snapshot.Path
+	if err := snapshot.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(copied); !os.IsNotExist(err) {
+		t.Fatalf("snapshot copy should be removed on close, got %v", err)
+	}
+}
+
+func TestOpenCancel(t *testing.T) {
+	path := newDatabase(t)
+	writer, err := Open(path, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	cancel := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := Open(path, Options{ReadOnly: true, Cancel: cancel})
+		done <- err
+	}()
+	time.Sleep(150 * time.Millisecond)
+	close(cancel)
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCanceled) {
+			t.Fatalf("expected ErrCanceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("open kept waiting for the lock after it was cancelled")
+	}
+}
+
+func TestImporterReplace(t *testing.T) {
+	path := newDatabase(t)
+	db, err := Open(path, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	count := func() int {
+		n, err := db.Count("people")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// a replace that fails leaves the collection as it was
+	failed, err := db.NewImporter("people", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := failed.Replace(); err != nil {
+		t.Fatal(err)
+	}
+	if err := failed.Write([][]byte{[]byte("bob")}, []any{map[string]any{"name": "Bob"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := failed.Abort(); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("expected the collection to keep its document, it has %v", n)
+	}
+
+	importer, err := db.NewImporter("people", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := importer.Replace(); err != nil {
+		t.Fatal(err)
+	}
+	docs := []any{map[string]any{"name": "Bob"}, map[string]any{"name": "Cy"}}
+	if err := importer.Write([][]byte{[]byte("bob"), nil}, docs); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("the collection shouldn't change before the commit, it has %v documents", n)
+	}
+	if err := importer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	want := ImportResult{Created: 2, Removed: 1}
+	if importer.Result != want {
+		t.Fatalf("expected %+v, got %+v", want, importer.Result)
+	}
+	if _, err := db.RawGet([][]byte{[]byte("people")}, []byte("ada")); err == nil {
+		t.Fatal("ada should have been replaced")
+	}
+	if _, err := db.RawGet([][]byte{[]byte("people")}, []byte("bob")); err != nil {
+		t.Fatalf("bob should have been imported: %v", err)
+	}
+	if _, err := db.RawList([][]byte{[]byte(stagingPrefix + "people")}); err == nil {
+		t.Fatal("the staging bucket should be gone after the commit")
+	}
+}
+
+func TestInsertRecordsFields(t *testing.T) {
+	path := newDatabase(t)
+	db, err := Open(path, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.RegisterCollection("people", []string{"Name" + bingo.FIELD_ALIAS_SEPARATOR + "name"}); err != nil {
+		t.Fatal(err)
+	}
+	docs := []any{map[string]any{"name": "Bob", "city": "Paris"}, map[string]any{"age": 3}}
+	if _, err := db.Insert("people", [][]byte{[]byte("bob"), nil}, docs, false); err != nil {
+		t.Fatal(err)
+	}
+	fields, err := db.FieldsOf("people")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"Name", "name"}, {"city"}, {"age"}}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("expected fields %v, got %v", want, fields)
+	}
+}
+
+type emptyDoc struct {
+	ID string `json:"id"`
+}
+
+func (d emptyDoc) Key() []byte { return []byte(d.ID) }
+
+// a collection bingo registered that never got a document has no bucket, it reads as empty
+func TestCollectionWithoutBucket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.db")
+	driver, err := bingo.NewDriver(bingo.DriverConfiguration{Filename: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bingo.CollectionFrom[emptyDoc](driver, "empty")
+	driver.Close()
+
+	db, err := Open(path, Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	collections, err := db.GetCollections()
+	if err != nil || !reflect.DeepEqual(collections, []string{"empty"}) {
+		t.Fatalf("collections %v: %v", collections, err)
+	}
+	err = db.db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket([]byte("empty")) != nil {
+			t.Fatal("the collection has a bucket, the test needs one without")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	visit := func(key []byte, _ *emptyDoc) error {
+		t.Errorf("got document %s", key)
+		return nil
+	}
+	q := bingo.Query[emptyDoc]{}
+	if err := Find(db, "empty", q, visit); err != nil {
+		t.Errorf("Find: %v", err)
+	}
+	if next, err := Page(db, "empty", q, nil, visit); next != nil || err != nil {
+		t.Errorf("Page: %s %v", next, err)
+	}
+	if more, err := PageAbove(db, "empty", q, nil, visit); more || err != nil {
+		t.Errorf("PageAbove: %v %v", more, err)
+	}
+	if count, err := db.Count("empty"); count != 0 || err != nil {
+		t.Errorf("Count: %v %v", count, err)
+	}
+	if prints, err := db.Fingerprints("empty"); len(prints) != 0 || err != nil {
+		t.Errorf("Fingerprints: %v %v", prints, err)
+	}
+	if _, err := Get[emptyDoc](db, "empty", []byte("a")); !errors.Is(err, bingo.ErrDocumentNotFound) {
+		t.Errorf("Get: %v", err)
+	}
+	if err := db.Delete("empty", [][]byte{[]byte("a")}); !errors.Is(err, bingo.ErrDocumentNotFound) {
+		t.Errorf("Delete: %v", err)
+	}
+	// collections bingo doesn't know are still an error
+	if _, err := db.Count("missing"); err == nil {
+		t.Error("Count of an unknown collection succeeded")
+	}
+
+	// the first insert creates the bucket
+	if _, err := db.Insert("empty", [][]byte{[]byte("a")}, []any{map[string]any{"id": "a"}}, false); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := db.Count("empty"); count != 1 || err != nil {
+		t.Errorf("Count after insert: %v %v", count, err)
+	}
+}
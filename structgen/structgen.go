@@ -0,0 +1,451 @@
+package structgen
+
+import (
+	"bingoviewer/store"
+	"bytes"
+	"fmt"
+	"github.com/nokusukun/bingo"
+	"go/format"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Sample collects the shape of documents, feed it documents with Add and turn it into Go code with Generate.
+type Sample struct {
+	// Docs is the number of documents added.
+	Docs int
+
+	root *shape
+	// keyFields are the top level fields whose value was the document key in every document seen so far
+	keyFields map[string]bool
+}
+
+// shape is everything seen at one position of the documents.
+type shape struct {
+	nulls, bools, ints, floats, strings, times, arrays, objects int
+
+	// elem is the shape of the array elements
+	elem *shape
+	// fields are the fields of the objects in the order they were first seen
+	fields  []string
+	members map[string]*shape
+	// present counts the objects each field was set in
+	present map[string]int
+}
+
+func newShape() *shape {
+	return &shape{members: map[string]*shape{}, present: map[string]int{}}
+}
+
+func New() *Sample {
+	return &Sample{root: newShape()}
+}
+
+// Add records the fields and values of a document stored under key.
+func (s *Sample) Add(key []byte, doc map[string]any) {
+	if s.Docs == 0 {
+		s.keyFields = map[string]bool{}
+		for name, v := range doc {
+			if str, ok := v.(string); ok && str == string(key) {
+				s.keyFields[name] = true
+			}
+		}
+	} else {
+		for name := range s.keyFields {
+			if str, ok := doc[name].(string); !ok || str != string(key) {
+				delete(s.keyFields, name)
+			}
+		}
+	}
+	s.Docs++
+	s.root.add(doc)
+}
+
+func (sh *shape) add(v any) {
+	switch v := v.(type) {
+	case nil:
+		sh.nulls++
+	case bool:
+		sh.bools++
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			sh.ints++
+		} else {
+			sh.floats++
+		}
+	case fmt.Stringer:
+		// json.Number when the documents were decoded keeping numbers as written
+		if strings.ContainsAny(v.String(), ".eE") {
+			sh.floats++
+		} else {
+			sh.ints++
+		}
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			sh.times++
+		} else {
+			sh.strings++
+		}
+	case []any:
+		sh.arrays++
+		if sh.elem == nil {
+			sh.elem = newShape()
+		}
+		for _, e := range v {
+			sh.elem.add(e)
+		}
+	case map[string]any:
+		sh.objects++
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			member, ok := sh.members[name]
+			if !ok {
+				member = newShape()
+				sh.members[name] = member
+				sh.fields = append(sh.fields, name)
+			}
+			sh.present[name]++
+			member.add(v[name])
+		}
+	}
+}
+
+// Options configure the generated code.
+type Options struct {
+	// Package is written as the package clause, no clause is written when it is empty.
+	Package string
+	// Name is the name of the document type.
+	Name string
+	// Fields are the field names and aliases bingo recorded for the collection, the Go name first and
+	// the json name last. They give the fields their names and order, fields that were never seen in
+	// the sample are typed any.
+	Fields [][]string
+}
+
+// generator writes the struct of the documents and the structs of the objects nested in them.
+type generator struct {
+	out      bytes.Buffer
+	pending  []pendingStruct
+	names    map[string]bool
+	needTime bool
+}
+
+type pendingStruct struct {
+	name  string
+	shape *shape
+	// fields of the document type, nil for nested structs
+	fields [][]string
+}
+
+// Generate returns gofmt'ed Go code declaring the document type with a Key method, so it can be used
+// with bingo.CollectionFrom.
+func (s *Sample) Generate(opts Options) ([]byte, error) {
+	name := exportedName(opts.Name)
+	g := &generator{names: map[string]bool{name: true}}
+	g.pending = append(g.pending, pendingStruct{name: name, shape: s.root, fields: opts.Fields})
+
+	var body bytes.Buffer
+	for i := 0; i < len(g.pending); i++ {
+		p := g.pending[i]
+		g.out.Reset()
+		if i == 0 {
+			fmt.Fprintf(&g.out, "// %v was generated from %v sampled document(s).\n", p.name, s.Docs)
+		}
+		members := g.writeStruct(p)
+		if i == 0 {
+			s.writeKey(&g.out, p.name, members)
+		}
+		body.Write(g.out.Bytes())
+	}
+
+	var src bytes.Buffer
+	if opts.Package != "" {
+		fmt.Fprintf(&src, "package %v\n\n", opts.Package)
+	}
+	if g.needTime {
+		src.WriteString("import \"time\"\n\n")
+	}
+	src.Write(body.Bytes())
+	if opts.Package == "" {
+		// go/format needs a package clause, it is added for formatting and cut off again
+		formatted, err := format.Source(append([]byte("package p\n\n"), src.Bytes()...))
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimPrefix(formatted, []byte("package p\n\n")), nil
+	}
+	return format.Source(src.Bytes())
+}
+
+// member is a field of a generated struct, typ is filled in once the struct is written.
+type member struct {
+	goName   string
+	jsonName string
+	shape    *shape
+	typ      string
+}
+
+// writeStruct writes the struct of p and returns its fields.
+func (g *generator) writeStruct(p pendingStruct) []member {
+	var members []member
+	used := map[string]bool{}
+	listed := map[string]bool{}
+	add := func(goName, jsonName string, sh *shape) {
+		goName = unique(exportedName(goName), used)
+		members = append(members, member{goName: goName, jsonName: jsonName, shape: sh})
+	}
+
+	for _, aliases := range p.fields {
+		if aliases[len(aliases)-1] == "-" {
+			// never written to the documents
+			continue
+		}
+		var jsonName string
+		for _, alias := range aliases {
+			if alias == "" || alias == "-" {
+				continue
+			}
+			listed[alias] = true
+			if jsonName == "" && p.shape.members[alias] != nil {
+				jsonName = alias
+			}
+		}
+		if jsonName == "" {
+			jsonName = aliases[len(aliases)-1]
+			if jsonName == "" || jsonName == "-" {
+				jsonName = aliases[0]
+			}
+		}
+		add(aliases[0], jsonName, p.shape.members[jsonName])
+	}
+	var extra []string
+	for _, name := range p.shape.fields {
+		if !listed[name] {
+			extra = append(extra, name)
+		}
+	}
+	if p.fields != nil {
+		sort.Strings(extra)
+	}
+	for _, name := range extra {
+		add(name, name, p.shape.members[name])
+	}
+
+	fmt.Fprintf(&g.out, "type %v struct {\n", p.name)
+	for i := range members {
+		m := &members[i]
+		optional := m.shape == nil || p.shape.present[m.jsonName] < p.shape.objects || m.shape.nulls > 0
+		typ := "any"
+		if m.shape != nil {
+			typ = g.typeOf(m.shape, p.name+m.goName)
+		}
+		if optional && !nilable(typ) {
+			typ = "*" + typ
+		}
+		m.typ = typ
+		tag := m.jsonName
+		if optional {
+			tag += ",omitempty"
+		}
+		comment := ""
+		if m.shape == nil {
+			comment = " // never seen in the sample"
+		}
+		fmt.Fprintf(&g.out, "\t%v %v `json:%q`%v\n", m.goName, typ, tag, comment)
+	}
+	fmt.Fprintf(&g.out, "}\n\n")
+	return members
+}
+
+// typeOf picks the Go type for the values of a shape, objects become a struct named name.
+func (g *generator) typeOf(sh *shape, name string) string {
+	kinds := 0
+	for _, n := range []int{sh.bools, sh.ints + sh.floats, sh.strings + sh.times, sh.arrays, sh.objects} {
+		if n > 0 {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		// never seen with a value or seen with values of different kinds
+		return "any"
+	}
+	switch {
+	case sh.bools > 0:
+		return "bool"
+	case sh.floats > 0:
+		return "float64"
+	case sh.ints > 0:
+		return "int"
+	case sh.strings > 0:
+		return "string"
+	case sh.times > 0:
+		g.needTime = true
+		return "time.Time"
+	case sh.arrays > 0:
+		elemName, ok := singular(name)
+		if !ok {
+			elemName = name + "Item"
+		}
+		elem := g.typeOf(sh.elem, elemName)
+		if sh.elem.nulls > 0 && !nilable(elem) {
+			elem = "*" + elem
+		}
+		return "[]" + elem
+	}
+	if len(sh.fields) == 0 {
+		return "map[string]any"
+	}
+	name = unique(name, g.names)
+	g.pending = append(g.pending, pendingStruct{name: name, shape: sh})
+	return name
+}
+
+// writeKey writes the Key method. It returns the string field that held the document key in every
+// sampled document, or nil to let bingo generate keys.
+func (s *Sample) writeKey(out *bytes.Buffer, name string, members []member) {
+	var key *member
+	for _, f := range s.root.fields {
+		if !s.keyFields[f] || s.root.present[f] != s.Docs {
+			continue
+		}
+		for i := range members {
+			if members[i].jsonName == f && members[i].typ == "string" {
+				key = &members[i]
+				break
+			}
+		}
+		if key != nil {
+			break
+		}
+	}
+	receiver := string(unicode.ToLower([]rune(name)[0]))
+	if key == nil {
+		fmt.Fprintf(out, "// Key returns the key the document is stored under, bingo generates one when it is empty.\n")
+		fmt.Fprintf(out, "func (%v %v) Key() []byte {\n\treturn nil\n}\n", receiver, name)
+		return
+	}
+	fmt.Fprintf(out, "// Key returns the key the document is stored under, %v held the key in every sampled document.\n", key.goName)
+	fmt.Fprintf(out, "func (%v %v) Key() []byte {\n\treturn []byte(%v.%v)\n}\n", receiver, name, receiver, key.goName)
+}
+
+func nilable(typ string) bool {
+	return typ == "any" || strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[")
+}
+
+// initialisms are written in upper case in Go names.
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "API": true, "HTTP": true, "HTTPS": true, "JSON": true,
+	"UUID": true, "IP": true, "SQL": true, "HTML": true, "CSS": true, "UI": true, "TTL": true,
+}
+
+// exportedName turns a field name like user_id or created-at into an exported Go name like UserID or CreatedAt.
+func exportedName(s string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteRune(unicode.ToUpper(r[0]))
+		b.WriteString(string(r[1:]))
+	}
+	name := b.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		return "F" + name
+	}
+	return name
+}
+
+// unique appends a number to name until it isn't in used, and marks the result as used.
+func unique(name string, used map[string]bool) string {
+	candidate := name
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%v%v", name, i)
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// document is what the sampled documents are decoded into.
+type document map[string]any
+
+func (document) Key() []byte {
+	return nil
+}
+
+// Collection samples the newest documents of a collection, every document when sample is 0, and generates
+// their type. The fields bingo recorded for the collection are used unless opts has its own, the type is
+// named after the collection unless opts names it.
+func Collection(db *store.DB, collection string, sample int, opts Options) ([]byte, error) {
+	if opts.Fields == nil {
+		// collections written without a struct have no recorded fields
+		opts.Fields, _ = db.FieldsOf(collection)
+	}
+	if opts.Name == "" {
+		opts.Name = TypeName(collection)
+	}
+	s := New()
+	err := store.Find(db, collection, bingo.Query[document]{Count: sample}, func(key []byte, doc *document) error {
+		s.Add(key, *doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Generate(opts)
+}
+
+// TypeName names the document type of a collection, users holds User documents.
+func TypeName(collection string) string {
+	name := exportedName(collection)
+	if s, ok := singular(name); ok {
+		return s
+	}
+	return name
+}
+
+// singular guesses the singular of an English plural, false if name doesn't look like one.
+func singular(name string) (string, bool) {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y", true
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"), strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "shes"):
+		return strings.TrimSuffix(name, "es"), true
+	case strings.HasSuffix(name, "s") && !strings.HasSuffix(name, "ss") && len(name) > 1:
+		return strings.TrimSuffix(name, "s"), true
+	}
+	return name, false
+}
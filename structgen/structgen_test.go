@@ -0,0 +1,119 @@
+package structgen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportedName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"name", "Name"},
+		{"user_id", "UserID"},
+		{"created-at", "CreatedAt"},
+		{"createdAt", "CreatedAt"},
+		{"apiURL", "APIURL"},
+		{"2fa", "F2fa"},
+		{"__", "Field"},
+	}
+	for _, tt := range tests {
+		if got := exportedName(tt.in); got != tt.want {
+			t.Errorf("exportedName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTypeName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"users", "User"},
+		{"categories", "Category"},
+		{"boxes", "Box"},
+		{"address", "Address"},
+		{"people", "People"},
+	}
+	for _, tt := range tests {
+		if got := TypeName(tt.in); got != tt.want {
+			t.Errorf("TypeName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func generate(t *testing.T, fields [][]string, docs map[string]map[string]any) string {
+	t.Helper()
+	s := New()
+	for key, doc := range docs {
+		s.Add([]byte(key), doc)
+	}
+	src, err := s.Generate(Options{Name: "User", Fields: fields})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// gofmt aligns the fields, the tests compare them with single spaces
+	lines := strings.Split(string(src), "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestGenerate(t *testing.T) {
+	src := generate(t, nil, map[string]map[string]any{
+		"ada": {"handle": "ada", "age": float64(36), "tags": []any{"a"}, "profile": map[string]any{"city": "London"}},
+		"bob": {"handle": "bob", "age": float64(41), "tags": []any{}, "profile": map[string]any{"city": "Paris"}, "nick": nil},
+	})
+	for _, want := range []string{
+		"type User struct {",
+		"Age int `json:\"age\"`",
+		"Nick any `json:\"nick,omitempty\"`",
+		"Profile UserProfile `json:\"profile\"`",
+		"Tags []string `json:\"tags\"`",
+		"type UserProfile struct {",
+		"return []byte(u.Handle)",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected %q in\n%v", want, src)
+		}
+	}
+}
+
+func TestGenerateKeyUsesRenamedField(t *testing.T) {
+	// ID and id both become ID, the second one is renamed and the key method has to use that name
+	src := generate(t, nil, map[string]map[string]any{
+		"k1": {"ID": "other", "id": "k1"},
+		"k2": {"ID": "other", "id": "k2"},
+	})
+	if !strings.Contains(src, "ID2 string `json:\"id\"`") || !strings.Contains(src, "return []byte(u.ID2)") {
+		t.Fatalf("the key method should use the renamed field:\n%v", src)
+	}
+}
+
+func TestGenerateKeyNeedsStringField(t *testing.T) {
+	// a key that parses as a time gives a time.Time field, which can't be turned into the key as it is
+	src := generate(t, nil, map[string]map[string]any{
+		"2024-01-02T03:04:05Z": {"at": "2024-01-02T03:04:05Z"},
+	})
+	if !strings.Contains(src, "At time.Time") || !strings.Contains(src, "return nil") {
+		t.Fatalf("expected a time field and a key method returning nil:\n%v", src)
+	}
+}
+
+func TestGenerateRecordedFields(t *testing.T) {
+	// recorded fields give the order and Go names, fields never seen are typed any
+	fields := [][]string{{"Name", "name"}, {"Email", "email"}, {"Secret", "-"}}
+	src := generate(t, fields, map[string]map[string]any{
+		"ada": {"name": "Ada", "extra": true},
+	})
+	name, email, extra := strings.Index(src, "Name "), strings.Index(src, "Email "), strings.Index(src, "Extra ")
+	if name < 0 || email < name || extra < email {
+		t.Fatalf("expected Name, Email and Extra in that order:\n%v", src)
+	}
+	if strings.Contains(src, "Secret") {
+		t.Fatalf("fields tagged - are never written and shouldn't be generated:\n%v", src)
+	}
+	if !strings.Contains(src, "// never seen in the sample") {
+		t.Fatalf("expected Email to be marked as never seen:\n%v", src)
+	}
+}
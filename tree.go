@@ -0,0 +1,365 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/termenv"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	treeStringStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#98c379"))
+	treeNumberStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#d19a66"))
+	treeBoolStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#c678dd"))
+	treeNullStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#474747"))
+	treePunctStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#7f7f7f"))
+	treeReturnStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#e07a00"))
+)
+
+// treeNode is a line of the document view, a field of the document, a member of an object or an array element.
+type treeNode struct {
+	// path is where the value sits in the document, dotted for members and indexed for elements: tags[1]
+	path  string
+	label string
+	value any
+	depth int
+	// field is the top level field the node belongs to, width the widest label among its siblings
+	field int
+	width int
+}
+
+// children returns how many members or elements a node holds, ok is false for scalars.
+func (n treeNode) children() (count int, ok bool) {
+	switch v := n.value.(type) {
+	case map[string]any:
+		return len(v), true
+	case []any:
+		return len(v), true
+	}
+	return 0, false
+}
+
+// TREE_OPEN_LIMIT is how many members or elements a top level field can hold and still start open.
+const TREE_OPEN_LIMIT = 20
+
+// treeOpen reports whether a node shows its children, top level fields are open until they are collapsed
+// unless they hold more than TREE_OPEN_LIMIT children.
+func (m Model) treeOpen(n treeNode) bool {
+	if open, ok := m.treeExpanded[n.path]; ok {
+		return open
+	}
+	count, _ := n.children()
+	return n.depth == 0 && count <= TREE_OPEN_LIMIT
+}
+
+// treeNodes lists the visible nodes of the shown document, children of collapsed nodes are left out.
+func (m Model) treeNodes() []treeNode {
+	fields := m.recordFields()
+	width := 0
+	for _, f := range fields {
+		width = max(width, len(f.name))
+	}
+	var nodes []treeNode
+	for i, f := range fields {
+		nodes = m.appendNode(nodes, treeNode{path: f.key, label: f.name, value: f.value, field: i, width: width})
+	}
+	return nodes
+}
+
+func (m Model) appendNode(nodes []treeNode, n treeNode) []treeNode {
+	nodes = append(nodes, n)
+	if !m.treeOpen(n) {
+		return nodes
+	}
+	switch v := n.value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		width := 0
+		for k := range v {
+			keys = append(keys, k)
+			width = max(width, len(k))
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			nodes = m.appendNode(nodes, treeNode{path: n.path + "." + k, label: k, value: v[k], depth: n.depth + 1, field: n.field, width: width})
+		}
+	case []any:
+		width := len(fmt.Sprintf("[%v]", len(v)-1))
+		for i, e := range v {
+			nodes = m.appendNode(nodes, treeNode{path: fmt.Sprintf("%v[%v]", n.path, i), label: fmt.Sprintf("[%v]", i), value: e, depth: n.depth + 1, field: n.field, width: width})
+		}
+	}
+	return nodes
+}
+
+// treeNode returns the node under the tree cursor.
+func (m Model) treeNode() (treeNode, bool) {
+	nodes := m.treeNodes()
+	if len(nodes) == 0 {
+		return treeNode{}, false
+	}
+	return nodes[max(0, min(m.treeCursor, len(nodes)-1))], true
+}
+
+// moveTreeCursor moves the cursor of the document view between the visible nodes.
+func (m *Model) moveTreeCursor(delta int) {
+	n := len(m.treeNodes())
+	m.treeCursor = max(0, min(m.treeCursor+delta, n-1))
+}
+
+// focusField puts the tree cursor on a top level field of the document.
+func (m *Model) focusField(field int) {
+	for i, n := range m.treeNodes() {
+		if n.depth == 0 && n.field == field {
+			m.treeCursor = i
+			return
+		}
+	}
+	m.treeCursor = 0
+}
+
+// focusPath opens the nodes above a path and puts the tree cursor on it, or on the field it is in.
+func (m *Model) focusPath(path string) {
+	for i, r := range path {
+		if (r == '.' || r == '[') && i > 0 {
+			m.treeExpanded[path[:i]] = true
+		}
+	}
+	for i, n := range m.treeNodes() {
+		if n.path == path {
+			m.treeCursor = i
+			return
+		}
+	}
+	for i, n := range m.treeNodes() {
+		if n.depth == 0 && strings.HasPrefix(path, n.path) {
+			m.treeCursor = i
+			return
+		}
+	}
+	m.treeCursor = 0
+}
+
+// focusColumn puts the tree cursor on the value a table column shows, or on the field it came from.
+func (m *Model) focusColumn(column int) {
+	if column < 0 || column >= len(m.columns) {
+		m.treeCursor = 0
+		return
+	}
+	for i, n := range m.treeNodes() {
+		for _, alias := range m.columns[column] {
+			if n.path == alias {
+				m.treeCursor = i
+				return
+			}
+		}
+	}
+	// the key comes first in the document view
+	m.focusField(m.columnFields[column] + 1)
+}
+
+// expandNode opens the node under the cursor, or steps into it when it is open already.
+func (m *Model) expandNode() {
+	n, ok := m.treeNode()
+	if count, container := n.children(); !ok || !container || count == 0 {
+		return
+	}
+	if m.treeOpen(n) {
+		m.moveTreeCursor(1)
+		return
+	}
+	m.treeExpanded[n.path] = true
+}
+
+// collapseNode closes the node under the cursor, or steps out to its parent when it is closed already.
+func (m *Model) collapseNode() {
+	n, ok := m.treeNode()
+	if !ok {
+		return
+	}
+	if count, container := n.children(); container && count > 0 && m.treeOpen(n) {
+		m.treeExpanded[n.path] = false
+		return
+	}
+	nodes := m.treeNodes()
+	for i := min(m.treeCursor, len(nodes)-1); i >= 0; i-- {
+		if nodes[i].depth < n.depth {
+			m.treeCursor = i
+			return
+		}
+	}
+}
+
+// toggleNode opens or closes the node under the cursor.
+func (m *Model) toggleNode() {
+	n, ok := m.treeNode()
+	if count, container := n.children(); ok && container && count > 0 {
+		m.treeExpanded[n.path] = !m.treeOpen(n)
+	}
+}
+
+// copyNodeValue puts the value under the tree cursor on the clipboard, strings as they are and anything else as JSON.
+func (m *Model) copyNodeValue() {
+	n, ok := m.treeNode()
+	if !ok {
+		return
+	}
+	value, isString := n.value.(string)
+	if !isString {
+		r, err := json.MarshalIndent(n.value, "", "  ")
+		if err != nil {
+			m.Error(fmt.Sprintf("Copy failed: %v", err))
+			return
+		}
+		value = string(r)
+	}
+	termenv.Copy(value)
+	m.Success(fmt.Sprintf("Copied the value of %v to the clipboard", n.path))
+}
+
+// copyNodePath puts the path of the node under the tree cursor on the clipboard.
+func (m *Model) copyNodePath() {
+	n, ok := m.treeNode()
+	if !ok {
+		return
+	}
+	termenv.Copy(n.path)
+	m.Success(fmt.Sprintf("Copied %v to the clipboard", n.path))
+}
+
+// stepRecord shows the document delta rows further down the table, or the first or last one when it runs past them.
+// The tree cursor stays on the same path when the next document has it.
+func (m *Model) stepRecord(delta int) {
+	n, _ := m.treeNode()
+	_, y := m.table.GetCursorLocation()
+	m.setCursor(max(0, min(y+delta, len(m.rowData)-1)))
+	m.syncRecord()
+	for i, node := range m.treeNodes() {
+		if node.path == n.path {
+			m.treeCursor = i
+			return
+		}
+	}
+}
+
+// layoutDocument fills the viewport with the document tree, follow scrolls it so the tree cursor is in view.
+func (m *Model) layoutDocument(follow bool) {
+	m.viewport.Width = m.window.width - 2
+	m.viewport.Height = m.window.height - 8
+	nodes := m.treeNodes()
+	m.treeCursor = max(0, min(m.treeCursor, len(nodes)-1))
+	invalid := m.invalidPaths(m.recordKey)
+
+	_, y := m.table.GetCursorLocation()
+	position, total := "?", fmt.Sprint(len(m.rowData))
+	if p, ok := m.rowPosition(y); ok {
+		position = fmt.Sprint(p)
+	}
+	if !m.loadedFirst || !m.loadedAll {
+		total = "?"
+		if m.rowCount >= 0 {
+			total = fmt.Sprint(m.rowCount)
+		}
+	}
+	lines := []string{fmt.Sprintf("Table: %v [%v/%v]", m.collections[m.activeCollection], position, total), ""}
+	cursorStart, cursorEnd := 0, 0
+	for i, n := range nodes {
+		if i == m.treeCursor {
+			cursorStart = len(lines)
+		}
+		line := m.renderNode(n, i == m.treeCursor, invalid[n.path] || (n.depth == 0 && invalid[n.label]))
+		lines = append(lines, strings.Split(wordwrap.String(line, m.viewport.Width-4), "\n")...)
+		if i == m.treeCursor {
+			cursorEnd = len(lines)
+		}
+	}
+	m.viewport.SetContent(strings.Join(lines, "\n"))
+	switch {
+	case !follow:
+	case m.treeCursor == 0:
+		m.viewport.SetYOffset(0)
+	case cursorStart < m.viewport.YOffset:
+		m.viewport.SetYOffset(cursorStart)
+	case cursorEnd > m.viewport.YOffset+m.viewport.Height:
+		m.viewport.SetYOffset(cursorEnd - m.viewport.Height)
+	}
+}
+
+func (m Model) renderNode(n treeNode, selected, invalid bool) string {
+	labelStyle := logoStyle
+	if strings.HasPrefix(n.label, "[") && n.depth > 0 {
+		labelStyle = treePunctStyle.Copy().PaddingLeft(1)
+	}
+	if invalid {
+		labelStyle = labelStyle.Copy().Foreground(invalidCellStyle.GetForeground())
+	}
+	if selected {
+		labelStyle = accentStyle.Copy().PaddingRight(0)
+	}
+	label := labelStyle.Render(n.label)
+	indent := strings.Repeat("  ", n.depth)
+	pad := strings.Repeat(" ", max(0, n.width-len(n.label)))
+	return fmt.Sprintf("%v%v%v : %v", indent, label, pad, m.renderValue(n))
+}
+
+// renderValue colours a value by its JSON type, objects and arrays show how many children they hold.
+func (m Model) renderValue(n treeNode) string {
+	if count, container := n.children(); container {
+		open, close := "{", "}"
+		if _, ok := n.value.([]any); ok {
+			open, close = "[", "]"
+		}
+		if count == 0 {
+			return treePunctStyle.Render(open + close)
+		}
+		marker := "▸"
+		if m.treeOpen(n) {
+			marker = "▾"
+		}
+		return treePunctStyle.Render(fmt.Sprintf("%v %v%v%v", marker, open, count, close))
+	}
+	switch v := n.value.(type) {
+	case nil:
+		return treeNullStyle.Render("null")
+	case bool:
+		return treeBoolStyle.Render(fmt.Sprint(v))
+	case float64:
+		return treeNumberStyle.Render(strconv.FormatFloat(v, 'f', -1, 64))
+	case json.Number:
+		return treeNumberStyle.Render(v.String())
+	case string:
+		// line breaks are shown as a return sign and a real line break
+		parts := strings.Split(v, "\n")
+		for i, part := range parts {
+			var b bytes.Buffer
+			enc := json.NewEncoder(&b)
+			enc.SetEscapeHTML(false)
+			_ = enc.Encode(part)
+			quoted := strings.TrimSuffix(b.String(), "\n")
+			if i > 0 {
+				quoted = strings.TrimPrefix(quoted, `"`)
+			}
+			if i < len(parts)-1 {
+				quoted = strings.TrimSuffix(quoted, `"`)
+			}
+			parts[i] = treeStringStyle.Render(strings.Map(func(r rune) rune {
+				if unicode.IsPrint(r) {
+					return r
+				}
+				return -1
+			}, quoted))
+		}
+		return strings.Join(parts, treeReturnStyle.Render("↵")+"\n")
+	}
+	r, err := json.Marshal(n.value)
+	if err != nil {
+		return errorStyle.Render(err.Error())
+	}
+	return string(r)
+}
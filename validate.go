@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bingoviewer/schema"
+	"bingoviewer/store"
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/nokusukun/bingo"
+	"path/filepath"
+	"strings"
+)
+
+var invalidCellStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff5555"))
+
+// validation is the schema attached to a collection and the result of the last pass over it.
+type validation struct {
+	path    string
+	checker schema.Checker
+	seq     int
+	running bool
+	checked int
+	// failures are the documents breaking the schema newest first, byKey holds their violations
+	failures []docFailure
+	byKey    map[string][]schema.Violation
+	err      error
+}
+
+type docFailure struct {
+	key        string
+	violations []schema.Violation
+}
+
+// validatedMsg carries the result of a validation pass running in the background.
+type validatedMsg struct {
+	collection string
+	seq        int
+	checked    int
+	failures   []docFailure
+	err        error
+}
+
+// validateCollection checks every document of the collection against the checker.
+func validateCollection(db *store.DB, collection string, checker schema.Checker) (int, []docFailure, error) {
+	checked := 0
+	var failures []docFailure
+	err := store.Find(db, collection, bingo.Query[kmap]{}, func(key []byte, doc *kmap) error {
+		checked++
+		if violations := checker.Check(*doc); len(violations) > 0 {
+			failures = append(failures, docFailure{key: string(key), violations: violations})
+		}
+		return nil
+	})
+	return checked, failures, err
+}
+
+func (m Model) showValidation() (tea.Model, tea.Cmd) {
+	if m.driver == nil || len(m.collections) == 0 {
+		return m, nil
+	}
+	if m.validations[m.collections[m.activeCollection]] == nil {
+		return m.showValidatePrompt()
+	}
+	m.state = ValidationPanel
+	return m, nil
+}
+
+func (m Model) showValidatePrompt() (tea.Model, tea.Cmd) {
+	collection := m.collections[m.activeCollection]
+	m.schemaInput = textinput.New()
+	m.schemaInput.Prompt = "validate against: "
+	m.schemaInput.Placeholder = collection + ".schema.json or types.go:User"
+	if v := m.validations[collection]; v != nil {
+		m.schemaInput.SetValue(v.path)
+		m.schemaInput.CursorEnd()
+	}
+	m.state = ValidatePrompt
+	return m, m.schemaInput.Focus()
+}
+
+func (m Model) updateValidatePrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Escape):
+		m.state = Normal
+		m.schemaInput.Blur()
+		return m, nil
+	case key.Matches(msg, m.keys.Enter):
+		path := strings.TrimSpace(m.schemaInput.Value())
+		if path == "" {
+			return m, nil
+		}
+		checker, err := schema.Load(path)
+		if err != nil {
+			m.Error(fmt.Sprintf("Can't use %v: %v", path, err))
+			return m, nil
+		}
+		m.schemaInput.Blur()
+		m.validations[m.collections[m.activeCollection]] = &validation{path: path, checker: checker}
+		return m.runValidation()
+	}
+	var cmd tea.Cmd
+	m.schemaInput, cmd = m.schemaInput.Update(msg)
+	return m, cmd
+}
+
+// runValidation starts a pass over the active collection and shows the validation panel.
+func (m Model) runValidation() (tea.Model, tea.Cmd) {
+	collection := m.collections[m.activeCollection]
+	v := m.validations[collection]
+	v.seq++
+	v.running = true
+	m.validationCursor = 0
+	m.state = ValidationPanel
+
+	db, checker, seq := m.driver, v.checker, v.seq
+	return m, func() tea.Msg {
+		checked, failures, err := validateCollection(db, collection, checker)
+		return validatedMsg{collection: collection, seq: seq, checked: checked, failures: failures, err: err}
+	}
+}
+
+func (m Model) validated(msg validatedMsg) (tea.Model, tea.Cmd) {
+	v := m.validations[msg.collection]
+	if v == nil || v.seq != msg.seq {
+		return m, nil
+	}
+	v.running = false
+	v.checked, v.failures, v.err = msg.checked, msg.failures, msg.err
+	v.byKey = map[string][]schema.Violation{}
+	for _, f := range v.failures {
+		v.byKey[f.key] = f.violations
+	}
+	if msg.err != nil {
+		m.Error(fmt.Sprintf("Validation failed: %v", msg.err))
+	} else if len(v.failures) == 0 {
+		m.Success(fmt.Sprintf("All %v document(s) of %v match %v", v.checked, msg.collection, filepath.Base(v.path)))
+	} else {
+		m.Error(fmt.Sprintf("%v of %v document(s) of %v don't match %v", len(v.failures), v.checked, msg.collection, filepath.Base(v.path)))
+	}
+	if m.collections[m.activeCollection] == msg.collection {
+		m.rebuildTable()
+	}
+	return m, nil
+}
+
+// lines flattens the failures to one line per violation.
+func (v *validation) lines() []validationLine {
+	var lines []validationLine
+	for _, f := range v.failures {
+		for _, violation := range f.violations {
+			lines = append(lines, validationLine{key: f.key, violation: violation})
+		}
+	}
+	return lines
+}
+
+type validationLine struct {
+	key       string
+	violation schema.Violation
+}
+
+func (m Model) updateValidationPanel(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	collection := m.collections[m.activeCollection]
+	v := m.validations[collection]
+	lines := v.lines()
+	page := max(1, m.window.height-14)
+
+	switch {
+	case key.Matches(msg, m.keys.Escape, m.keys.Validate):
+		m.state = Normal
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+	case key.Matches(msg, m.keys.Up):
+		m.validationCursor = max(0, m.validationCursor-1)
+	case key.Matches(msg, m.keys.Down):
+		m.validationCursor = min(len(lines)-1, m.validationCursor+1)
+	case key.Matches(msg, m.keys.PgUp):
+		m.validationCursor = max(0, m.validationCursor-page)
+	case key.Matches(msg, m.keys.PgDn):
+		m.validationCursor = min(len(lines)-1, m.validationCursor+page)
+	case msg.String() == "r":
+		if !v.running {
+			return m.runValidation()
+		}
+	case msg.String() == "c":
+		return m.showValidatePrompt()
+	case msg.String() == "u":
+		delete(m.validations, collection)
+		m.state = Normal
+		m.rebuildTable()
+		m.Info(fmt.Sprintf("Detached %v from %v", filepath.Base(v.path), collection))
+		return m, m.ClearInfoAfter("3s")
+	case key.Matches(msg, m.keys.Enter):
+		if m.validationCursor < 0 || m.validationCursor >= len(lines) {
+			break
+		}
+		line := lines[m.validationCursor]
+		m.state = Normal
+		return m, m.jumpTo(rowJump{
+			key:     []byte(line.key),
+			path:    line.violation.Path,
+			missing: fmt.Sprintf("%v isn't in the table, it doesn't match the current filter", line.key),
+		})
+	}
+	return m, nil
+}
+
+// moveToColumn puts the table cursor on the column showing a path, or on the first column of a flattened field.
+func (m *Model) moveToColumn(path string) {
+	column := -1
+	for i, aliases := range m.columns {
+		if invalidColumn(aliases, map[string]bool{path: true}) {
+			column = i
+			break
+		}
+		for _, alias := range aliases {
+			if column < 0 && strings.HasPrefix(alias, path+".") {
+				column = i
+			}
+		}
+	}
+	if column < 0 {
+		return
+	}
+	x, _ := m.table.GetCursorLocation()
+	for ; x < column; x++ {
+		m.table.CursorRight()
+	}
+	for ; x > column; x-- {
+		m.table.CursorLeft()
+	}
+}
+
+// invalidPaths returns the paths of a document that break the attached schema and the top level fields they are in.
+func (m Model) invalidPaths(key []byte) map[string]bool {
+	if len(m.collections) == 0 {
+		return nil
+	}
+	v := m.validations[m.collections[m.activeCollection]]
+	if v == nil || len(v.byKey[string(key)]) == 0 {
+		return nil
+	}
+	paths := map[string]bool{}
+	for _, violation := range v.byKey[string(key)] {
+		paths[violation.Path] = true
+		paths[violation.Field()] = true
+	}
+	return paths
+}
+
+// invalidColumn reports whether a column shows one of the invalid paths or holds one of them further down.
+func invalidColumn(aliases []string, paths map[string]bool) bool {
+	for path := range paths {
+		for _, alias := range aliases {
+			if path == alias || strings.HasPrefix(path, alias+".") || strings.HasPrefix(path, alias+"[") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m Model) RenderValidationPanel() string {
+	collection := m.collections[m.activeCollection]
+	v := m.validations[collection]
+	title := fmt.Sprintf("Validating %v against %v", logoStyle.Copy().PaddingLeft(0).Render(collection), v.path)
+	help := "[enter] go to document    [r] run again    [c] change schema    [u] detach    [esc] close"
+	switch {
+	case v.running:
+		return fmt.Sprintf("%v\n\nChecking...", title)
+	case v.err != nil:
+		return fmt.Sprintf("%v\n\n%v\n\n%v", title, errorStyle.Render(v.err.Error()), help)
+	case len(v.failures) == 0:
+		return fmt.Sprintf("%v\n\n%v\n\n%v", title, successStyle.Render(fmt.Sprintf("All %v document(s) match", v.checked)), help)
+	}
+
+	lines := v.lines()
+	height := max(1, m.window.height-14)
+	// keep the cursor in the middle of the list once it scrolls
+	start := max(0, min(m.validationCursor-height/2, len(lines)-height))
+	end := min(len(lines), start+height)
+
+	keyWidth, pathWidth, ruleWidth := len(KEY_COLUMN), len("FIELD"), len("RULE")
+	for _, line := range lines[start:end] {
+		keyWidth = max(keyWidth, len(line.key))
+		pathWidth = max(pathWidth, len(line.violation.Path))
+		ruleWidth = max(ruleWidth, len(line.violation.Rule))
+	}
+	format := fmt.Sprintf("%%-%vv  %%-%vv  %%-%vv  %%v", keyWidth, pathWidth, ruleWidth)
+	var b strings.Builder
+	b.WriteString(logoStyle.Copy().PaddingLeft(0).Render(fmt.Sprintf(format, KEY_COLUMN, "FIELD", "RULE", "MESSAGE")) + "\n")
+	for i := start; i < end; i++ {
+		line := fmt.Sprintf(format, lines[i].key, lines[i].violation.Path, lines[i].violation.Rule, lines[i].violation.Message)
+		if i == m.validationCursor {
+			line = accentStyle.Copy().Padding(0).Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	summary := fmt.Sprintf("%v of %v document(s) don't match, %v violation(s)", len(v.failures), v.checked, len(lines))
+	return fmt.Sprintf("%v\n%v\n\n%v\n%v", title, summary, b.String(), help)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bingoviewer/store"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"os"
+	"time"
+)
+
+// WATCH_INTERVAL is how often watch mode looks at the database file.
+const WATCH_INTERVAL = time.Second
+
+var (
+	watchAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#55ff55"))
+	watchChangedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#e5c07b"))
+)
+
+// fileStamp is what watch mode compares to tell that the database file was written to.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// watch follows the database file while watch mode is on. prints are the fingerprints of the documents
+// of collection at the last reload, added and changed the keys that reload found new or different.
+type watch struct {
+	seq        int
+	stamp      fileStamp
+	collection string
+	prints     map[string]uint64
+	added      map[string]bool
+	changed    map[string]bool
+	// busy is set while a reload runs in the background
+	busy bool
+}
+
+// watchTickMsg asks the watch with the same seq to look at the database file again.
+type watchTickMsg struct {
+	seq int
+}
+
+// watchReloadedMsg carries the database reopened after the file changed and the fingerprints of the
+// collection, from is the database the reload started from so a reload that was overtaken is dropped.
+type watchReloadedMsg struct {
+	from       *store.DB
+	db         *store.DB
+	stamp      fileStamp
+	reload     bool
+	collection string
+	prints     map[string]uint64
+	err        error
+}
+
+func tickWatch(seq int) tea.Cmd {
+	return tea.Tick(WATCH_INTERVAL, func(time.Time) tea.Msg {
+		return watchTickMsg{seq: seq}
+	})
+}
+
+// toggleWatch turns watch mode on or off.
+func (m *Model) toggleWatch() tea.Cmd {
+	if m.watch != nil {
+		m.watch = nil
+		m.Info("Stopped watching the database file")
+		return m.ClearInfoAfter("3s")
+	}
+	m.watchSeq++
+	m.watch = &watch{seq: m.watchSeq}
+	m.resetWatch()
+	if m.DatabaseFile != "" {
+		m.Info(fmt.Sprintf("Watching %v for changes", m.DatabaseFile))
+	}
+	return tea.Batch(tickWatch(m.watchSeq), m.ClearInfoAfter("3s"))
+}
+
+// resetWatch starts watching the open database from its current state.
+func (m *Model) resetWatch() {
+	w := m.watch
+	if w == nil {
+		return
+	}
+	w.stamp, _ = statFile(m.DatabaseFile)
+	w.collection, w.prints, w.added, w.changed = "", nil, nil, nil
+	w.busy = false
+}
+
+// watchTick reloads the database in the background when the file changed since the last look,
+// or takes the fingerprints of a collection that was switched to so its next changes can be told apart.
+// Nothing is reloaded while a prompt, panel or transfer is open.
+func (m Model) watchTick(msg watchTickMsg) (tea.Model, tea.Cmd) {
+	w := m.watch
+	if w == nil || msg.seq != w.seq {
+		return m, nil
+	}
+	next := tickWatch(w.seq)
+	if w.busy || m.driver == nil || len(m.collections) == 0 || m.state != Normal || m.transfer != nil {
+		return m, next
+	}
+	stamp, err := statFile(m.DatabaseFile)
+	if err != nil {
+		// the file is being replaced, it is looked at again on the next tick
+		return m, next
+	}
+	collection := m.collections[m.activeCollection]
+	reload := stamp != w.stamp
+	if !reload && w.collection == collection && w.prints != nil {
+		return m, next
+	}
+
+	w.busy = true
+	from, opts := m.driver, m.backgroundOptions()
+	return m, tea.Batch(next, func() tea.Msg {
+		return reloadWatched(from, opts, reload, collection, stamp)
+	})
+}
+
+// reloadWatched reopens the database if the file changed and fingerprints the collection. A snapshot is
+// copied again and a read-only database opened again, a database opened for writing can only have been
+// changed by us so it is read as it is.
+func reloadWatched(from *store.DB, opts store.Options, reload bool, collection string, stamp fileStamp) watchReloadedMsg {
+	msg := watchReloadedMsg{from: from, db: from, stamp: stamp, reload: reload, collection: collection}
+	if reload && from.IsSnapshot() {
+		msg.db, msg.err = store.OpenSnapshot(from.Source, opts)
+	} else if reload && from.ReadOnly {
+		msg.db, msg.err = store.Open(from.Path, opts)
+	}
+	if msg.err != nil {
+		msg.db = nil
+		return msg
+	}
+	msg.prints, msg.err = msg.db.Fingerprints(collection)
+	if msg.err != nil && msg.db != from {
+		_ = msg.db.Close()
+		msg.db = nil
+	}
+	return msg
+}
+
+func (m Model) watchReloaded(msg watchReloadedMsg) (tea.Model, tea.Cmd) {
+	w := m.watch
+	if w == nil || m.driver != msg.from {
+		if msg.db != nil && msg.db != msg.from {
+			_ = msg.db.Close()
+		}
+		return m, nil
+	}
+	w.busy = false
+	// a file that failed to reload is tried again once it changes another time, not on every tick
+	w.stamp = msg.stamp
+	if msg.err != nil {
+		m.Error(fmt.Sprintf("Reload failed: %v", msg.err))
+		return m, nil
+	}
+	if msg.db != m.driver {
+		_ = m.driver.Close()
+		m.driver = msg.db
+	}
+
+	old, baseline := w.prints, w.collection == msg.collection && w.prints != nil
+	w.collection, w.prints = msg.collection, msg.prints
+	if !msg.reload {
+		return m, nil
+	}
+	w.added, w.changed = map[string]bool{}, map[string]bool{}
+	removed := 0
+	if baseline {
+		for k, p := range msg.prints {
+			if o, ok := old[k]; !ok {
+				w.added[k] = true
+			} else if o != p {
+				w.changed[k] = true
+			}
+		}
+		for k := range old {
+			if _, ok := msg.prints[k]; !ok {
+				removed++
+			}
+		}
+	}
+	for k := range m.selected {
+		if _, ok := msg.prints[k]; !ok && msg.collection == m.collections[m.activeCollection] {
+			delete(m.selected, k)
+		}
+	}
+
+	cmd := m.refreshCollection()
+	if len(w.added)+len(w.changed)+removed > 0 {
+		m.Info(fmt.Sprintf("%v changed on disk: %v added, %v changed, %v removed", msg.collection, len(w.added), len(w.changed), removed))
+		cmd = tea.Batch(cmd, m.ClearInfoAfter("3s"))
+	}
+	return m, cmd
+}
+
+// refreshCollection reads the active collection again and keeps the cursor, sort and filter,
+// the cursor stays on its row number when the record it was on is gone.
+func (m *Model) refreshCollection() tea.Cmd {
+	active := m.collections[m.activeCollection]
+	colls, err := m.driver.GetCollections()
+	if err != nil {
+		m.Error(fmt.Sprintf("Failed to get collections: %v", err))
+		return nil
+	}
+	m.collections = colls
+	found := false
+	for i, coll := range colls {
+		if coll == active {
+			m.activeCollection, found = i, true
+			break
+		}
+	}
+	if !found {
+		m.activeCollection = 0
+		m.showRecord = false
+		if len(colls) == 0 {
+			return nil
+		}
+		m.staleSorts()
+		return m.loadCollection()
+	}
+
+	current, _ := m.cursorKey()
+	if err := m.layoutColumns(); err != nil {
+		m.Error(fmt.Sprintf("Failed to get columns: %v", err))
+		return nil
+	}
+	m.visualAnchor = -1
+	m.visualBase = nil
+	// the window opens on the record again, or on the row below it that took its place
+	sort := m.resort()
+	load := m.reloadRows(current)
+
+	// the document view reads the document again even when the cursor stayed on it
+	m.recordKey = nil
+	return tea.Batch(load, m.recount(), sort)
+}
+
+// changeStyle returns the colour of a row that watch mode saw added or changed at the last reload.
+func (m Model) changeStyle(key string) (lipgloss.Style, bool) {
+	w := m.watch
+	if w == nil || len(m.collections) == 0 || w.collection != m.collections[m.activeCollection] {
+		return lipgloss.Style{}, false
+	}
+	switch {
+	case w.added[key]:
+		return watchAddedStyle, true
+	case w.changed[key]:
+		return watchChangedStyle, true
+	}
+	return lipgloss.Style{}, false
+}